@@ -1,44 +1,16 @@
 package main
 
 import (
-	"flag"
 	"fmt"
 	"os"
 	"path/filepath"
-	"reflect"
-	"strings"
 )
 
-// ヘルプメッセージを表示する関数
-func printHelp() {
-	fmt.Println("Usage: splashscreen-changer [options]")
-	fmt.Println("Options:")
-	flag.PrintDefaults()
-	fmt.Println("Environment Variables:")
-	fmt.Printf("  %-20s %s\n", "CONFIG_PATH", "Path to the configuration file (default: data/config.yml)")
-
-	// Config 構造体のフィールドから環境変数のキーを生成して表示
-	configType := reflect.TypeOf(Config{})
-	for i := 0; i < configType.NumField(); i++ {
-		section := configType.Field(i)
-		sectionType := section.Type
-
-		for j := 0; j < sectionType.NumField(); j++ {
-			field := sectionType.Field(j)
-			helpTag := field.Tag.Get("help")
-			envKey := strings.ToUpper(section.Name + "_" + field.Name)
-			defaultValue := field.Tag.Get("default")
-			if defaultValue != "" {
-				helpTag += fmt.Sprintf(" (default: %s)", defaultValue)
-			}
-			fmt.Printf("  %-20s %s\n", envKey, helpTag)
-		}
-	}
-
-	fmt.Println()
-	fmt.Println("GitHub: https://github.com/tomacheese/splashscreen-changer")
-	fmt.Println("Booth: https://tomachi.booth.pm/items/6284870")
-}
+// ピクチャフォルダのパスを取得する関数。テストでは一時フォルダを返す関数に置き換える
+var (
+	picturesLegacyPathFunc = getPicturesLegacyPath
+	picturesPathFunc       = getPicturesPath
+)
 
 func getSourcePath(config *Config) (string, error) {
 	// 取得の優先度は以下。
@@ -56,8 +28,8 @@ func getSourcePath(config *Config) (string, error) {
 	errorRequired := fmt.Errorf("source.path is required")
 
 	// ユーザーフォルダの Pictures フォルダ内に VRChat フォルダが存在するか確認
-	picturesLegacyPath, errLegacy := getPicturesLegacyPath()
-	picturesNewPath, err := getPicturesPath()
+	picturesLegacyPath, errLegacy := picturesLegacyPathFunc()
+	picturesNewPath, err := picturesPathFunc()
 	if errLegacy != nil && err != nil {
 		return "", errorRequired
 	}
@@ -69,7 +41,7 @@ func getSourcePath(config *Config) (string, error) {
 
 	vrchatPath := filepath.Join(picturesPath, "VRChat")
 	if _, err := os.Stat(vrchatPath); err == nil {
-		return vrchatPath, errorRequired
+		return vrchatPath, nil
 	}
 
 	return "", errorRequired
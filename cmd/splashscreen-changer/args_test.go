@@ -0,0 +1,38 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Test that getSourcePath detects the VRChat folder in the Pictures folder
+func TestGetSourcePath(t *testing.T) {
+	pictures := t.TempDir()
+	legacyPath, path := picturesLegacyPathFunc, picturesPathFunc
+	t.Cleanup(func() { picturesLegacyPathFunc, picturesPathFunc = legacyPath, path })
+	picturesLegacyPathFunc = func() (string, error) { return "", errors.New("not found") }
+	picturesPathFunc = func() (string, error) { return pictures, nil }
+
+	var config Config
+	// No VRChat folder in the Pictures folder
+	if got, err := getSourcePath(&config); err == nil {
+		t.Errorf("Expected an error without a VRChat folder, got %q", got)
+	}
+
+	vrchat := filepath.Join(pictures, "VRChat")
+	if err := os.Mkdir(vrchat, 0755); err != nil {
+		t.Fatal(err)
+	}
+	got, err := getSourcePath(&config)
+	if err != nil || got != vrchat {
+		t.Errorf("Expected %s without an error, got %q, %v", vrchat, got, err)
+	}
+
+	// source.path takes precedence over the detected folder
+	config.Source.Path = t.TempDir()
+	if got, err := getSourcePath(&config); err != nil || got != config.Source.Path {
+		t.Errorf("Expected %s, got %q, %v", config.Source.Path, got, err)
+	}
+}
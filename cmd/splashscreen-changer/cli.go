@@ -0,0 +1,583 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// command は、サブコマンドの定義です。ヘルプメッセージは、この定義とフラグの定義から生成します。
+type command struct {
+	// Name は、コマンド名。"config validate" のように空白で区切って複数の単語にできる
+	Name string
+	// Args は、ヘルプメッセージに表示する引数の書式
+	Args string
+	// Summary は、コマンドの説明
+	Summary string
+	// UsesConfig は、設定ファイルを読み込むコマンドか。true の場合は -config フラグを追加し、ヘルプメッセージに環境変数を表示する
+	UsesConfig bool
+	// WritesLog は、ログファイルにもログを出力するコマンドか
+	WritesLog bool
+	// SupportsJSON は、-json フラグで実行結果を JSON 形式で出力できるコマンドか
+	SupportsJSON bool
+	// Setup は、コマンド固有のフラグを定義し、コマンドを実行する関数を返す
+	Setup func(fs *flag.FlagSet) commandFunc
+}
+
+// commandFunc は、フラグの解析後にコマンドを実行する関数です。
+type commandFunc func(ctx *commandContext) error
+
+// commandContext は、コマンドの実行に必要な値です。
+type commandContext struct {
+	// Args は、フラグ以外の引数
+	Args []string
+	// Config は、読み込んだ設定値。UsesConfig が false のコマンドでは nil
+	Config *Config
+	// ConfigPath は、設定ファイルのパス
+	ConfigPath string
+	// Loaded は、設定ファイルの読み込み結果（警告、読み込んだ設定ファイル、各設定値を指定した場所）。UsesConfig が false のコマンドでは nil
+	Loaded *loadedConfig
+	// ReloadConfig は、設定ファイルを起動時と同じ引数で読み込み直す関数。UsesConfig が false のコマンドでは nil
+	ReloadConfig func() (*loadedConfig, error)
+	// In は、コマンドの入力元（config init コマンドの質問への回答）
+	In io.Reader
+	// Out は、コマンドの出力先
+	Out io.Writer
+	// LogFile は、ログを出力するログファイル。WritesLog が false のコマンドでは nil
+	LogFile *logFile
+	// JSON は、-json フラグが指定されたか
+	JSON bool
+	// jsonWritten は、コマンドが実行結果を JSON 形式で出力済みか。エラーの場合も出力済みであれば、エラーの JSON を出力しない
+	jsonWritten bool
+}
+
+// サブコマンドを指定しない場合に実行するコマンド
+const defaultCommand = "run"
+
+// commands は、すべてのサブコマンドの一覧です。ヘルプメッセージには、この順序で表示します。
+var commands []*command
+
+func init() {
+	commands = []*command{
+		{
+			Name:         "run",
+			Summary:      "Change the splash screen to a randomly picked image (default command)",
+			UsesConfig:   true,
+			WritesLog:    true,
+			SupportsJSON: true,
+			Setup: func(fs *flag.FlagSet) commandFunc {
+				reindex := fs.Bool("reindex", false, "Rebuild the library index from scratch before picking")
+				return func(ctx *commandContext) error {
+					return runChangeCommand(ctx, *reindex)
+				}
+			},
+		},
+		{
+			Name:       "daemon",
+			Summary:    "Keep running and change the splash screen at an interval, reloading the configuration file when it is edited",
+			UsesConfig: true,
+			WritesLog:  true,
+			Setup: func(fs *flag.FlagSet) commandFunc {
+				interval := fs.Duration("interval", time.Hour, "Interval between changes of the splash screen (e.g. 30m, 6h)")
+				return func(ctx *commandContext) error {
+					return runDaemon(ctx, *interval)
+				}
+			},
+		},
+		{
+			Name:       "preview",
+			Summary:    "Pick an image and write the resulting splash screen to a file without changing the current one",
+			UsesConfig: true,
+			Setup: func(fs *flag.FlagSet) commandFunc {
+				output := fs.String("output", "preview.png", "Path to the preview image")
+				return func(ctx *commandContext) error {
+					return runPreview(ctx, *output)
+				}
+			},
+		},
+		{
+			Name:         "list",
+			Summary:      "List the source images matching the filters",
+			UsesConfig:   true,
+			SupportsJSON: true,
+			Setup: func(fs *flag.FlagSet) commandFunc {
+				return runList
+			},
+		},
+		{
+			Name:         "current",
+			Summary:      "Show the source image of the current splash screen",
+			UsesConfig:   true,
+			SupportsJSON: true,
+			Setup: func(fs *flag.FlagSet) commandFunc {
+				return runCurrent
+			},
+		},
+		{
+			Name:         "history",
+			Summary:      "Show the history of picked images",
+			SupportsJSON: true,
+			Setup: func(fs *flag.FlagSet) commandFunc {
+				return runHistory
+			},
+		},
+		{
+			Name:       "restore",
+			Summary:    "Restore the original splash screen that was in place before this application changed it",
+			UsesConfig: true,
+			WritesLog:  true,
+			Setup: func(fs *flag.FlagSet) commandFunc {
+				return runRestore
+			},
+		},
+		{
+			Name:    "pick",
+			Args:    "<image path>",
+			Summary: "Use the specified image on the next run instead of picking randomly",
+			Setup: func(fs *flag.FlagSet) commandFunc {
+				return func(ctx *commandContext) error {
+					if len(ctx.Args) != 1 {
+						return errUsage
+					}
+					return updateForcedImage(ctx.Args[0], "", "", false)
+				}
+			},
+		},
+		{
+			Name:    "pin",
+			Args:    "<image path>",
+			Summary: "Keep using the specified image until the expiry or unpin",
+			Setup: func(fs *flag.FlagSet) commandFunc {
+				until := fs.String("until", "", "Expiry of the pin, as a date (2026-10-20), a time (2026-10-20 21:00) or a duration (36h)")
+				return func(ctx *commandContext) error {
+					if len(ctx.Args) != 1 {
+						return errUsage
+					}
+					return updateForcedImage("", ctx.Args[0], *until, false)
+				}
+			},
+		},
+		{
+			Name:    "unpin",
+			Summary: "Remove the pinned image",
+			Setup: func(fs *flag.FlagSet) commandFunc {
+				return func(ctx *commandContext) error {
+					return updateForcedImage("", "", "", true)
+				}
+			},
+		},
+		{
+			Name:    "tag",
+			Args:    "add <image path> <tag>... | remove <image path> <tag>... | list [image path]",
+			Summary: "Manage tags of source images",
+			Setup: func(fs *flag.FlagSet) commandFunc {
+				return runTag
+			},
+		},
+		{
+			Name:       "duplicates",
+			Summary:    "Report groups of near-duplicate images in the source directories",
+			UsesConfig: true,
+			Setup: func(fs *flag.FlagSet) commandFunc {
+				return runDuplicates
+			},
+		},
+		{
+			Name:       "config validate",
+			Summary:    "Check the configuration file and environment variables",
+			UsesConfig: true,
+			Setup: func(fs *flag.FlagSet) commandFunc {
+				return runConfigValidate
+			},
+		},
+		{
+			Name:    "config init",
+			Summary: "Create a configuration file by answering questions, with the detected source and destination paths as defaults",
+			Setup: func(fs *flag.FlagSet) commandFunc {
+				configPath := fs.String("config", os.Getenv("CONFIG_PATH"), "Path to the configuration file to create")
+				force := fs.Bool("force", false, "Overwrite the configuration file if it already exists")
+				yes := fs.Bool("yes", false, "Accept the detected defaults without asking questions")
+				return func(ctx *commandContext) error {
+					return runConfigInit(ctx, getConfigPath(configPath), *force, !*yes)
+				}
+			},
+		},
+		{
+			Name:         "config path",
+			Summary:      "Show which configuration file is used and the locations searched for it",
+			SupportsJSON: true,
+			Setup: func(fs *flag.FlagSet) commandFunc {
+				configPath := fs.String("config", "", "Path to the configuration file")
+				return func(ctx *commandContext) error {
+					return runConfigPath(ctx, *configPath)
+				}
+			},
+		},
+		{
+			Name:       "config show",
+			Summary:    "Print the configuration merged from the configuration file, its includes and config.local.yml",
+			UsesConfig: true,
+			Setup: func(fs *flag.FlagSet) commandFunc {
+				effective := fs.Bool("effective", false, "Print the final values including environment variables and defaults, with where each value came from")
+				return func(ctx *commandContext) error {
+					return runConfigShow(ctx, *effective)
+				}
+			},
+		},
+		{
+			Name:    "config schema",
+			Summary: "Print the JSON Schema of the configuration file for completion and validation in editors",
+			Setup: func(fs *flag.FlagSet) commandFunc {
+				output := fs.String("output", "", "Write the schema to this file instead of standard output")
+				return func(ctx *commandContext) error {
+					return runConfigSchema(ctx, *output)
+				}
+			},
+		},
+		{
+			Name:    "version",
+			Summary: "Show version",
+			Setup: func(fs *flag.FlagSet) commandFunc {
+				return runVersion
+			},
+		},
+		{
+			Name:    "help",
+			Args:    "[command]",
+			Summary: "Show help message",
+			Setup: func(fs *flag.FlagSet) commandFunc {
+				return runHelp
+			},
+		},
+	}
+}
+
+// errUsage は、コマンドの引数が正しくない場合のエラーです。コマンドのヘルプメッセージを表示します。
+var errUsage = errors.New("invalid arguments")
+
+// legacyFlag は、サブコマンドに置き換えた以前のバージョンの引数です（例: -current → current コマンド）。
+type legacyFlag struct {
+	Command string
+	// HasValue は、引数が値を取るか。値は、コマンドの最初の引数にする（例: -pin photo.png → pin photo.png）
+	HasValue bool
+	// FirstOnly は、最初の引数の場合のみ置き換えるか。run -reindex -help のように、ほかの引数の後の -help は run コマンドのヘルプを表示する
+	FirstOnly bool
+}
+
+// legacyFlags は、以前のバージョンの引数と、その引数を置き換えたコマンドの対応です。
+// -reindex は、run コマンドの引数としてそのまま使用できます。
+var legacyFlags = map[string]legacyFlag{
+	"help":            {Command: "help", FirstOnly: true},
+	"h":               {Command: "help", FirstOnly: true},
+	"version":         {Command: "version", FirstOnly: true},
+	"current":         {Command: "current"},
+	"history":         {Command: "history"},
+	"unpin":           {Command: "unpin"},
+	"find-duplicates": {Command: "duplicates"},
+	"pick":            {Command: "pick", HasValue: true},
+	"pin":             {Command: "pin", HasValue: true},
+	"tag":             {Command: "tag", HasValue: true},
+}
+
+// findLegacyCommand は、以前のバージョンの引数を探し、置き換えたコマンドと、そのコマンドの引数を返します。
+// 以前のバージョンの引数がない場合は nil を返します。
+func findLegacyCommand(args []string) (*command, []string) {
+	for i, arg := range args {
+		if arg == "--" || !strings.HasPrefix(arg, "-") {
+			continue
+		}
+		name, value, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		legacy, ok := legacyFlags[name]
+		if !ok || (legacy.FirstOnly && i > 0) {
+			continue
+		}
+
+		rest := append([]string{}, args[:i]...)
+		var positional []string
+		switch {
+		case legacy.HasValue && hasValue:
+			positional = []string{value}
+		case legacy.HasValue && i+1 < len(args):
+			positional = []string{args[i+1]}
+			i++
+		}
+		rest = append(rest, args[i+1:]...)
+		return lookupCommand(legacy.Command), append(positional, rest...)
+	}
+	return nil, nil
+}
+
+// findCommand は、引数からサブコマンドを探し、コマンドと残りの引数を返します。
+// 引数がない場合やフラグから始まる場合は、run コマンドとして扱います。
+func findCommand(args []string) (*command, []string, error) {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		// 以前のバージョンの -help・-version・-current などの引数は、それぞれのコマンドとして扱う
+		if cmd, rest := findLegacyCommand(args); cmd != nil {
+			return cmd, rest, nil
+		}
+		return lookupCommand(defaultCommand), args, nil
+	}
+
+	// "config validate" のような複数の単語のコマンドを優先する
+	var found *command
+	for _, cmd := range commands {
+		words := strings.Fields(cmd.Name)
+		if len(words) > len(args) || strings.Join(args[:len(words)], " ") != cmd.Name {
+			continue
+		}
+		if found == nil || len(words) > len(strings.Fields(found.Name)) {
+			found = cmd
+		}
+	}
+	if found == nil {
+		return nil, nil, withExitCode(exitUsage, fmt.Errorf("unknown command '%s'. Run 'splashscreen-changer help' for the list of commands", args[0]))
+	}
+	return found, args[len(strings.Fields(found.Name)):], nil
+}
+
+// lookupCommand は、指定された名前のコマンドを返します。
+func lookupCommand(name string) *command {
+	for _, cmd := range commands {
+		if cmd.Name == name {
+			return cmd
+		}
+	}
+	return nil
+}
+
+// commonFlags は、コマンドの定義から追加する共通のフラグです。コマンドが対応していないフラグは nil です。
+type commonFlags struct {
+	ConfigPath *string
+	Strict     *bool
+	JSON       *bool
+	// ConfigValues は、設定項目を上書きするコマンドライン引数（例: --destination.width）で指定された値。キーは設定項目のキー
+	ConfigValues map[string][]string
+}
+
+// newFlagSet は、コマンドのフラグを定義した FlagSet と、コマンドを実行する関数を返します。
+func (cmd *command) newFlagSet(out io.Writer) (*flag.FlagSet, commandFunc, commonFlags) {
+	fs := flag.NewFlagSet(cmd.Name, flag.ContinueOnError)
+	fs.SetOutput(out)
+
+	var common commonFlags
+	if cmd.UsesConfig {
+		common.ConfigPath = fs.String("config", "", "Path to the configuration file. If not specified, it is searched in the standard locations (see 'config path')")
+		strict, _ := strconv.ParseBool(os.Getenv("CONFIG_STRICT"))
+		common.Strict = fs.Bool("strict", strict, "Treat unknown keys in the configuration file as errors instead of warnings")
+		common.ConfigValues = addConfigFlags(fs)
+	}
+	if cmd.SupportsJSON {
+		common.JSON = fs.Bool("json", false, "Print the result as JSON to standard output (logs are written to standard error)")
+	}
+	run := cmd.Setup(fs)
+	fs.Usage = func() { printCommandHelp(out, cmd, fs) }
+	return fs, run, common
+}
+
+// parseFlags は、フラグを解析し、フラグ以外の引数を返します。
+// flag パッケージと異なり、フラグ以外の引数の後ろにあるフラグも解析します（例: pin photo.png -until 2026-10-20）。
+func parseFlags(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		if args[0] == "--" {
+			return append(positional, args[1:]...), nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// runCLI は、コマンドライン引数からサブコマンドを実行します。
+// 返すエラーの終了コードは exitCodeOf で取得できます。
+func runCLI(args []string, out io.Writer) error {
+	cmd, args, err := findCommand(args)
+	if err != nil {
+		return err
+	}
+
+	fs, run, common := cmd.newFlagSet(out)
+	positional, err := parseFlags(fs, args)
+	if errors.Is(err, flag.ErrHelp) {
+		return nil
+	}
+	if err != nil {
+		return withExitCode(exitUsage, err)
+	}
+
+	ctx := &commandContext{Args: positional, In: os.Stdin, Out: out}
+	if common.JSON != nil {
+		ctx.JSON = *common.JSON
+	}
+
+	err = runCommand(cmd, ctx, run, common)
+	if errors.Is(err, errUsage) {
+		printCommandHelp(out, cmd, fs)
+	}
+	// -json が指定された場合は、エラーも JSON 形式で出力する
+	if err != nil && ctx.JSON && !ctx.jsonWritten {
+		if writeErr := writeJSON(out, newErrorResult(err)); writeErr != nil {
+			slog.Error("Failed to write the result", "error", writeErr)
+		}
+	}
+	return err
+}
+
+// runCommand は、設定ファイルを読み込み、コマンドを実行します。
+func runCommand(cmd *command, ctx *commandContext, run commandFunc, common commonFlags) error {
+	if cmd.UsesConfig {
+		found, _ := findConfigFile(*common.ConfigPath)
+		ctx.ConfigPath = found.Path
+		slog.Info("Loading config file", "path", ctx.ConfigPath, "reason", found.Reason)
+		loaded, err := loadConfig(ctx.ConfigPath, *common.Strict, common.ConfigValues)
+		if err != nil {
+			// 設定値の問題は、位置付きですべて表示する
+			var configErr *configError
+			if errors.As(err, &configErr) && !ctx.JSON {
+				printConfigProblems(ctx.Out, configErr)
+			}
+			err = fmt.Errorf("failed to load configuration file: %w", err)
+			// ソースフォルダや保存先のフォルダが存在しない場合は、それぞれの終了コードを使用する
+			var exitErr *exitError
+			if !errors.As(err, &exitErr) {
+				err = withExitCode(exitConfigError, err)
+			}
+			return err
+		}
+		config := loaded.Config
+		ctx.Config = config
+		ctx.Loaded = loaded
+		ctx.ReloadConfig = func() (*loadedConfig, error) {
+			return loadConfig(ctx.ConfigPath, *common.Strict, common.ConfigValues)
+		}
+
+		if cmd.WritesLog {
+			logFile, err := openLogFile(config, ctx.JSON)
+			if err != nil {
+				return err
+			}
+			defer logFile.Close()
+			ctx.LogFile = logFile
+		} else if err := setupLogger(config, os.Stderr, nil); err != nil {
+			return err
+		}
+
+		for _, warning := range loaded.Warnings {
+			slog.Warn("Problem in the configuration file", "location", warning.location(ctx.ConfigPath), "problem", warning.Message)
+		}
+	}
+	return run(ctx)
+}
+
+// printCommandHelp は、コマンドのヘルプメッセージを表示します。
+func printCommandHelp(w io.Writer, cmd *command, fs *flag.FlagSet) {
+	usage := "Usage: splashscreen-changer " + cmd.Name
+	// 設定項目を上書きする引数は、Configuration Options としてまとめて表示する
+	options := flag.NewFlagSet(cmd.Name, flag.ContinueOnError)
+	fs.VisitAll(func(f *flag.Flag) {
+		if !isConfigFlag(f) {
+			options.Var(f.Value, f.Name, f.Usage)
+			options.Lookup(f.Name).DefValue = f.DefValue
+		}
+	})
+	hasFlags := false
+	options.VisitAll(func(*flag.Flag) { hasFlags = true })
+	if hasFlags {
+		usage += " [options]"
+	}
+	if cmd.Args != "" {
+		usage += " " + cmd.Args
+	}
+	fmt.Fprintln(w, usage)
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, cmd.Summary)
+
+	if hasFlags {
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "Options:")
+		options.SetOutput(w)
+		options.PrintDefaults()
+	}
+
+	if cmd.UsesConfig {
+		fmt.Fprintln(w)
+		printConfigFlagHelp(w)
+		fmt.Fprintln(w)
+		printEnvHelp(w)
+	}
+}
+
+// printHelp は、コマンドの一覧を含むヘルプメッセージを表示します。
+func printHelp(w io.Writer) {
+	fmt.Fprintln(w, "Usage: splashscreen-changer [command] [options]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-18s %s\n", cmd.Name, cmd.Summary)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "If no command is given, '%s' is executed.\n", defaultCommand)
+	fmt.Fprintln(w, "Run 'splashscreen-changer help <command>' for the options of each command.")
+
+	fmt.Fprintln(w)
+	printConfigFlagHelp(w)
+	fmt.Fprintln(w, "These options are available for the commands that read the configuration file.")
+
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "GitHub: https://github.com/tomacheese/splashscreen-changer")
+	fmt.Fprintln(w, "Booth: https://tomachi.booth.pm/items/6284870")
+}
+
+// printEnvHelp は、設定値を上書きできる環境変数の一覧を表示します。
+func printEnvHelp(w io.Writer) {
+	fmt.Fprintln(w, "Environment Variables:")
+	fmt.Fprintf(w, "  %-20s %s\n", "CONFIG_PATH", "Path to the configuration file. If not set, it is searched in the standard locations (see 'config path')")
+	fmt.Fprintf(w, "  %-20s %s\n", "CONFIG_STRICT", "Treat unknown keys in the configuration file as errors (default: false)")
+
+	// Config 構造体のフィールドから環境変数のキーを生成して表示
+	walkConfigFields(&Config{}, func(field configField) {
+		if field.EnvKey == "" {
+			return
+		}
+		fmt.Fprintf(w, "  %-20s %s\n", field.EnvKey, configFieldHelp(field))
+	})
+}
+
+// runHelp は、help コマンドを実行します。コマンドが指定された場合は、そのコマンドのヘルプメッセージを表示します。
+func runHelp(ctx *commandContext) error {
+	if len(ctx.Args) == 0 {
+		printHelp(ctx.Out)
+		return nil
+	}
+
+	cmd, rest, err := findCommand(ctx.Args)
+	if err != nil {
+		return err
+	}
+	if len(rest) > 0 {
+		return errUsage
+	}
+	fs, _, _ := cmd.newFlagSet(ctx.Out)
+	printCommandHelp(ctx.Out, cmd, fs)
+	return nil
+}
+
+// runVersion は、version コマンドを実行します。
+func runVersion(ctx *commandContext) error {
+	fmt.Fprintln(ctx.Out, "splashscreen-changer")
+	fmt.Fprintln(ctx.Out, "|- Version", GetAppVersion())
+	fmt.Fprintln(ctx.Out, "|- Build date:", GetAppDate())
+	return nil
+}
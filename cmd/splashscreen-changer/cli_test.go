@@ -0,0 +1,416 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestFindCommand(t *testing.T) {
+	tests := []struct {
+		args     []string
+		expected string
+		rest     []string
+	}{
+		{nil, "run", nil},
+		{[]string{"-config", "a.yml"}, "run", []string{"-config", "a.yml"}},
+		{[]string{"-help"}, "help", []string{}},
+		{[]string{"--version"}, "version", []string{}},
+		{[]string{"current", "-json"}, "current", []string{"-json"}},
+		{[]string{"config", "validate", "-config", "a.yml"}, "config validate", []string{"-config", "a.yml"}},
+		{[]string{"config", "init"}, "config init", []string{}},
+		{[]string{"tag", "add", "a.png", "sunset"}, "tag", []string{"add", "a.png", "sunset"}},
+		// Options of earlier versions are mapped to the commands that replaced them
+		{[]string{"-current", "-json"}, "current", []string{"-json"}},
+		{[]string{"-json", "-history"}, "history", []string{"-json"}},
+		{[]string{"-pin", "a.png", "-until", "36h"}, "pin", []string{"a.png", "-until", "36h"}},
+		{[]string{"-pick=a.png"}, "pick", []string{"a.png"}},
+		{[]string{"-unpin"}, "unpin", []string{}},
+		{[]string{"-tag", "add", "a.png", "sunset"}, "tag", []string{"add", "a.png", "sunset"}},
+		{[]string{"-config", "a.yml", "-find-duplicates"}, "duplicates", []string{"-config", "a.yml"}},
+		{[]string{"-reindex"}, "run", []string{"-reindex"}},
+		{[]string{"-reindex", "-help"}, "run", []string{"-reindex", "-help"}},
+	}
+
+	for _, tt := range tests {
+		cmd, rest, err := findCommand(tt.args)
+		if err != nil {
+			t.Errorf("%v: unexpected error: %v", tt.args, err)
+			continue
+		}
+		if cmd.Name != tt.expected {
+			t.Errorf("%v: expected command %s, got %s", tt.args, tt.expected, cmd.Name)
+		}
+		if strings.Join(rest, " ") != strings.Join(tt.rest, " ") {
+			t.Errorf("%v: expected rest %v, got %v", tt.args, tt.rest, rest)
+		}
+	}
+
+	for _, args := range [][]string{{"bogus"}, {"config"}, {"config", "bogus"}} {
+		if _, _, err := findCommand(args); err == nil {
+			t.Errorf("%v: expected an error", args)
+		}
+	}
+}
+
+func TestParseFlagsInterspersed(t *testing.T) {
+	fs := flag.NewFlagSet("pin", flag.ContinueOnError)
+	until := fs.String("until", "", "")
+	force := fs.Bool("force", false, "")
+
+	args, err := parseFlags(fs, []string{"photo.png", "-until", "36h", "other.png", "-force", "--", "-literal"})
+	if err != nil {
+		t.Fatalf("parseFlags failed: %v", err)
+	}
+	if *until != "36h" || !*force {
+		t.Errorf("Expected flags after positional arguments to be parsed, got until=%s force=%v", *until, *force)
+	}
+	if strings.Join(args, " ") != "photo.png other.png -literal" {
+		t.Errorf("Unexpected positional arguments: %v", args)
+	}
+}
+
+func TestCommandHelp(t *testing.T) {
+	var buf bytes.Buffer
+	if err := runCLI([]string{"help", "run"}, &buf); err != nil {
+		t.Fatalf("runCLI failed: %v", err)
+	}
+	help := buf.String()
+	for _, expected := range []string{"Usage: splashscreen-changer run [options]", "-config", "-reindex", "Environment Variables:", "SOURCE_PATH", "DESTINATION_WIDTH"} {
+		if !strings.Contains(help, expected) {
+			t.Errorf("Expected help of run to contain %q:\n%s", expected, help)
+		}
+	}
+
+	// Commands that do not read the configuration do not list environment variables
+	buf.Reset()
+	if err := runCLI([]string{"history", "-help"}, &buf); err != nil {
+		t.Fatalf("runCLI failed: %v", err)
+	}
+	if help := buf.String(); !strings.Contains(help, "-json") || strings.Contains(help, "SOURCE_PATH") || strings.Contains(help, "-config") {
+		t.Errorf("Unexpected help of history:\n%s", help)
+	}
+
+	// Every command appears in the command list
+	buf.Reset()
+	if err := runCLI([]string{"help"}, &buf); err != nil {
+		t.Fatalf("runCLI failed: %v", err)
+	}
+	for _, cmd := range commands {
+		if !strings.Contains(buf.String(), "  "+cmd.Name+" ") {
+			t.Errorf("Expected command list to contain %s", cmd.Name)
+		}
+	}
+	// The generated configuration options are listed in the general help
+	for _, expected := range []string{"Configuration Options", "--destination.width=value", "--source.recursive "} {
+		if !strings.Contains(buf.String(), expected) {
+			t.Errorf("Expected the help to contain %q:\n%s", expected, buf.String())
+		}
+	}
+}
+
+func TestRunCLIUsageError(t *testing.T) {
+	var buf bytes.Buffer
+	err := runCLI([]string{"pick"}, &buf)
+	if !errors.Is(err, errUsage) {
+		t.Errorf("Expected a usage error, got %v", err)
+	}
+	if !strings.Contains(buf.String(), "Usage: splashscreen-changer pick <image path>") {
+		t.Errorf("Expected the help of pick to be shown, got:\n%s", buf.String())
+	}
+
+	if err := runCLI([]string{"current", "-unknown"}, &buf); err == nil {
+		t.Error("Expected an error for an unknown flag")
+	}
+}
+
+func TestRunConfigInit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data", "config.yml")
+	var buf bytes.Buffer
+	if err := runCLI([]string{"config", "init", "-yes", "-config", path}, &buf); err != nil {
+		t.Fatalf("config init failed: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read created config: %v", err)
+	}
+	var created map[string]map[string]any
+	if err := yaml.Unmarshal(data, &created); err != nil {
+		t.Fatalf("Created config is not valid YAML: %v", err)
+	}
+	if created["destination"]["width"] != 800 || created["destination"]["height"] != 450 || created["source"]["recursive"] != true {
+		t.Errorf("Unexpected created config:\n%s", data)
+	}
+
+	// An existing file is not overwritten without -force
+	if err := runCLI([]string{"config", "init", "-yes", "-config", path}, &buf); err == nil {
+		t.Error("Expected an error for an existing config file")
+	}
+	if err := runCLI([]string{"config", "init", "-yes", "-config", path, "-force"}, &buf); err != nil {
+		t.Errorf("Expected -force to overwrite, got %v", err)
+	}
+}
+
+func TestBackupAndRestoreSplashScreen(t *testing.T) {
+	dir := t.TempDir()
+	destFile := filepath.Join(dir, "EasyAntiCheat", "SplashScreen.png")
+	backupPath := filepath.Join(dir, "data", "backup", "SplashScreen.png")
+	if err := os.MkdirAll(filepath.Dir(destFile), os.ModePerm); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+
+	// Nothing to back up when there is no splash screen yet
+	if err := backupSplashScreen(destFile, backupPath); err != nil {
+		t.Fatalf("backupSplashScreen failed: %v", err)
+	}
+	if _, err := os.Stat(backupPath); !os.IsNotExist(err) {
+		t.Error("Expected no backup without a splash screen")
+	}
+	if err := restoreSplashScreen(backupPath, destFile); err == nil {
+		t.Error("Expected an error when there is no backup")
+	}
+
+	// The original splash screen is backed up
+	writeTestPNG(t, destFile, 16, 9)
+	original, _ := os.ReadFile(destFile)
+	if err := backupSplashScreen(destFile, backupPath); err != nil {
+		t.Fatalf("backupSplashScreen failed: %v", err)
+	}
+
+	// A splash screen generated by this application does not replace the backup
+	srcPath := filepath.Join(dir, "source.png")
+	writeTestPNG(t, srcPath, 32, 18)
+	texts, err := splashScreenTexts(srcPath, time.Now(), false)
+	if err != nil {
+		t.Fatalf("splashScreenTexts failed: %v", err)
+	}
+	if err := resizePNGFile(srcPath, destFile, 16, 9, 0, texts...); err != nil {
+		t.Fatalf("resizePNGFile failed: %v", err)
+	}
+	if err := backupSplashScreen(destFile, backupPath); err != nil {
+		t.Fatalf("backupSplashScreen failed: %v", err)
+	}
+	backup, _ := os.ReadFile(backupPath)
+	if !bytes.Equal(backup, original) {
+		t.Error("Expected the backup to keep the original splash screen")
+	}
+
+	if err := restoreSplashScreen(backupPath, destFile); err != nil {
+		t.Fatalf("restoreSplashScreen failed: %v", err)
+	}
+	restored, _ := os.ReadFile(destFile)
+	if !bytes.Equal(restored, original) {
+		t.Error("Expected the original splash screen to be restored")
+	}
+}
+
+func TestExitCodeOf(t *testing.T) {
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{nil, exitOK},
+		{errors.New("something failed"), exitFailure},
+		{errUsage, exitUsage},
+		{withExitCode(exitNoFiles, errNoPNGFiles), exitNoFiles},
+		{fmt.Errorf("wrapped: %w", withExitCode(exitWriteFailed, errors.New("disk full"))), exitWriteFailed},
+	}
+	for _, tt := range tests {
+		if got := exitCodeOf(tt.err); got != tt.want {
+			t.Errorf("exitCodeOf(%v) = %d, want %d", tt.err, got, tt.want)
+		}
+	}
+	if withExitCode(exitFailure, nil) != nil {
+		t.Error("Expected withExitCode to return nil for a nil error")
+	}
+}
+
+// writeRunConfig writes a configuration file for the run command and returns its path.
+func writeRunConfig(t *testing.T, source, destination string) string {
+	t.Helper()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yml")
+	config := fmt.Sprintf("source:\n  path: %q\ndestination:\n  path: %q\nlog:\n  path: %q\n", source, destination, filepath.Join(dir, "app.log"))
+	if err := os.WriteFile(path, []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// openLogFile changes the default logger, so restore it after the test
+	logger := slog.Default()
+	t.Cleanup(func() { slog.SetDefault(logger) })
+	return path
+}
+
+func TestRunJSON(t *testing.T) {
+	fixture, err := os.ReadFile(filepath.Join("testdata", "VRChat_2026-01-02_03-04-05.678_16x9.png"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The library index and the state file are written to data/ in the working directory
+	t.Chdir(t.TempDir())
+
+	emptySource := t.TempDir()
+	source := t.TempDir()
+	if err := os.WriteFile(filepath.Join(source, "photo.png"), fixture, 0644); err != nil {
+		t.Fatal(err)
+	}
+	destination := t.TempDir()
+	if err := os.Mkdir(filepath.Join(destination, "EasyAntiCheat"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	// The splash screen cannot be written because a directory exists at its path
+	brokenDestination := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(brokenDestination, "EasyAntiCheat", "SplashScreen.png"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	missingDestination := filepath.Join(t.TempDir(), "missing")
+
+	invalidConfig := filepath.Join(t.TempDir(), "config.yml")
+	if err := os.WriteFile(invalidConfig, []byte("source: [\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		config   string
+		wantCode int
+	}{
+		{"success", writeRunConfig(t, source, destination), exitOK},
+		{"no files", writeRunConfig(t, emptySource, destination), exitNoFiles},
+		{"missing source", writeRunConfig(t, filepath.Join(emptySource, "missing"), destination), exitNoSource},
+		{"missing destination", writeRunConfig(t, source, missingDestination), exitNoDestination},
+		{"write failed", writeRunConfig(t, source, brokenDestination), exitWriteFailed},
+		{"invalid config", invalidConfig, exitConfigError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			err := runCLI([]string{"run", "-json", "-config", tt.config}, &buf)
+			if got := exitCodeOf(err); got != tt.wantCode {
+				t.Errorf("Expected exit code %d, got %d (%v)", tt.wantCode, got, err)
+			}
+
+			// The standard output must contain only the JSON result
+			var result runResult
+			if err := json.Unmarshal(buf.Bytes(), &result); err != nil {
+				t.Fatalf("Output is not a JSON result: %v\n%s", err, buf.String())
+			}
+			if result.ExitCode != tt.wantCode {
+				t.Errorf("Expected exit_code %d, got %d", tt.wantCode, result.ExitCode)
+			}
+			if tt.wantCode == exitOK {
+				if result.Status != "ok" || result.Error != "" {
+					t.Errorf("Unexpected result: %+v", result)
+				}
+				if result.Source != filepath.Join(source, "photo.png") || result.SourceWidth != 16 || result.SourceHeight != 9 {
+					t.Errorf("Unexpected source in result: %+v", result)
+				}
+				if result.Destination != filepath.Join(destination, "EasyAntiCheat", "SplashScreen.png") || result.Width != 800 || result.Height != 450 {
+					t.Errorf("Unexpected destination in result: %+v", result)
+				}
+			} else if result.Status != "error" || result.Error == "" {
+				t.Errorf("Expected an error result, got %+v", result)
+			}
+		})
+	}
+}
+
+func TestRunSkipsOversizedImages(t *testing.T) {
+	fixture, err := os.ReadFile(filepath.Join("testdata", "VRChat_2026-01-02_03-04-05.678_16x9.png"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir(t.TempDir())
+
+	source := t.TempDir()
+	if err := os.WriteFile(filepath.Join(source, "photo.png"), fixture, 0644); err != nil {
+		t.Fatal(err)
+	}
+	// Larger than source.max_pixels below
+	large, err := os.Create(filepath.Join(source, "large.png"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	png.Encode(large, image.NewGray(image.Rect(0, 0, 100, 100)))
+	large.Close()
+	// An interlaced PNG larger than largeImagePixels
+	writeInterlacedTestPNG(t, filepath.Join(source, "interlaced.png"), 5000, 5000)
+
+	destination := t.TempDir()
+	if err := os.Mkdir(filepath.Join(destination, "EasyAntiCheat"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	config := writeRunConfig(t, source, destination)
+	t.Setenv("SOURCE_MAX_PIXELS", "1000")
+
+	// Whichever image is picked first, the oversized ones are skipped and the usable one is used
+	for i := 0; i < 10; i++ {
+		var buf bytes.Buffer
+		if err := runCLI([]string{"run", "-json", "-config", config}, &buf); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		var result runResult
+		if err := json.Unmarshal(buf.Bytes(), &result); err != nil {
+			t.Fatal(err)
+		}
+		if result.Source != filepath.Join(source, "photo.png") {
+			t.Fatalf("Expected photo.png to be used, got %+v", result)
+		}
+	}
+
+	// When every image is too large, no image can be used
+	t.Setenv("SOURCE_MAX_PIXELS", "100")
+	err = runCLI([]string{"run", "-config", config}, io.Discard)
+	if got := exitCodeOf(err); got != exitNoFiles {
+		t.Errorf("Expected exit code %d, got %d (%v)", exitNoFiles, got, err)
+	}
+}
+
+func TestSelectImageExitCodes(t *testing.T) {
+	source := t.TempDir()
+	if err := os.WriteFile(filepath.Join(source, "photo.png"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		path     string
+		include  []string
+		wantCode int
+	}{
+		{"unreadable source", filepath.Join(source, "missing"), nil, exitNoSource},
+		{"invalid tag expression", source, []string{"sunset+"}, exitConfigError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var config Config
+			config.Tags.Include = tt.include
+			sources := []sourceSpec{{Path: tt.path, Weight: 1}}
+			_, err := selectImage(&config, sources, newLibraryIndex(""), &appState{}, false, nil)
+			if got := exitCodeOf(err); got != tt.wantCode {
+				t.Errorf("Expected exit code %d, got %d (%v)", tt.wantCode, got, err)
+			}
+		})
+	}
+}
+
+func TestRunCLIUnknownCommandExitCode(t *testing.T) {
+	var buf bytes.Buffer
+	err := runCLI([]string{"unknown-command"}, &buf)
+	if got := exitCodeOf(err); got != exitUsage {
+		t.Errorf("Expected exit code %d, got %d", exitUsage, got)
+	}
+}
@@ -0,0 +1,665 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// resolveSourcesVerbose は、ソースフォルダを取得します。取得できない場合は、取得の手順をログに出力します。
+func resolveSourcesVerbose(config *Config) ([]sourceSpec, error) {
+	sources, err := resolveSources(config)
+	if err != nil {
+		slog.Error("Failed to obtain source path", "error", err)
+		slog.Info("The following steps are used to obtain the source paths. This error occurs because the following steps could not be taken to obtain the source path.")
+		slog.Info("1. Environment variable SOURCE_PATH. If this is not set, the following steps are taken.")
+		slog.Info("2. source.path in Configuration file. If this is not set, the following steps are taken.")
+		slog.Info("3. Check if the VRChat folder exists in the Pictures folder in the user folder.")
+		slog.Info("If the VRChat folder exists, the path to the VRChat folder is used as the source path.")
+		return nil, err
+	}
+	return sources, nil
+}
+
+// getDestinationPathVerbose は、保存先のフォルダを取得します。取得できない場合は、取得の手順をログに出力します。
+func getDestinationPathVerbose(config *Config) (string, error) {
+	destinationPath, err := getDestinationPath(config)
+	if err != nil {
+		slog.Error("Failed to obtain destination path", "error", err)
+		slog.Info("The following steps are used to obtain the destination paths. This error occurs because the following steps could not be taken to obtain the destination path.")
+		slog.Info("1. Environment variable DESTINATION_PATH. If this is not set, the following steps are taken.")
+		slog.Info("2. destination.path in Configuration file. If this is not set, the following steps are taken.")
+		slog.Info("3. Get the installation destination folder of VRChat from the Steam library folder.")
+		slog.Info("If the EasyAntiCheat folder exists in the VRChat folder, the path to the VRChat folder is used as the destination path.")
+		return "", err
+	}
+	return destinationPath, nil
+}
+
+// splashScreenFile は、保存先のフォルダにあるスプラッシュスクリーンファイルのパスを返します。
+func splashScreenFile(destinationPath string) string {
+	return filepath.Join(destinationPath, "EasyAntiCheat", "SplashScreen.png")
+}
+
+// openLibraryIndex は、ライブラリインデックス（フォルダの構成、画像サイズやハッシュのキャッシュ）を読み込みます。
+// reindex が true の場合は、インデックスの内容を破棄します。
+func openLibraryIndex(reindex bool) *libraryIndex {
+	index, err := loadLibraryIndex(getDataFilePath("library.json"))
+	if err != nil {
+		slog.Warn("Failed to load library index, rebuilding", "error", err)
+	}
+	if reindex {
+		index.reset()
+	}
+	return index
+}
+
+// saveLibraryIndex は、ライブラリインデックスを保存します。保存に失敗した場合はログに出力します。
+func saveLibraryIndex(index *libraryIndex) {
+	if err := index.save(); err != nil {
+		slog.Warn("Failed to save library index", "error", err)
+	}
+}
+
+// selectImage は、ソースフォルダの画像をリストし、1 つの画像を選択します。
+// pick・pin コマンドで指定された画像があれば、ランダムに選択せずにその画像を使用します。
+// skipped に含まれる画像（大きすぎて読み込めなかった画像）は選択しません。
+// ソースフォルダを読み込めない場合は exitNoSource、絞り込み条件が正しくない場合は exitConfigError の終了コードを付けたエラーを返します。
+func selectImage(config *Config, sources []sourceSpec, index *libraryIndex, state *appState, reindex bool, skipped map[string]bool) (string, error) {
+	// ソースディレクトリ以下のPNGファイルをリストする
+	candidates, err := listCandidates(sources, index)
+	if err != nil {
+		return "", withExitCode(exitNoSource, err)
+	}
+
+	// インデックスを作り直す場合は、すべての画像の画像サイズとハッシュを計算する
+	if reindex {
+		slog.Info("Rebuilding library index", "files", len(candidates))
+		index.rebuild(candidatePaths(candidates))
+	}
+
+	if pickedFile, forced := state.forcedPick(time.Now()); forced && !skipped[pickedFile] {
+		return pickedFile, nil
+	}
+
+	if len(skipped) > 0 {
+		var remaining []candidate
+		for _, c := range candidates {
+			if !skipped[c.Path] {
+				remaining = append(remaining, c)
+			}
+		}
+		candidates = remaining
+	}
+	return pickFromLibrary(config, candidates, sources, index)
+}
+
+// pickAndResize は、selectImage で画像を選択し、resize で出力します。
+// 選択した画像が大きすぎて読み込めない場合（errImageTooLarge）は、その画像を除外して選択し直します。
+// selectImage のエラーは selectErr として、resize のエラーは resizeErr として返します。
+func pickAndResize(config *Config, sources []sourceSpec, index *libraryIndex, state *appState, reindex bool, resize func(pickedFile string) error) (pickedFile string, selectErr, resizeErr error) {
+	skipped := map[string]bool{}
+	for {
+		pickedFile, err := selectImage(config, sources, index, state, reindex && len(skipped) == 0, skipped)
+		if err != nil {
+			return "", err, nil
+		}
+		slog.Info("Picked file", "path", pickedFile)
+
+		err = resize(pickedFile)
+		if errors.Is(err, errImageTooLarge) {
+			slog.Warn("Picked image is too large to use, picking another image", "path", pickedFile, "error", err)
+			skipped[pickedFile] = true
+			continue
+		}
+		return pickedFile, nil, err
+	}
+}
+
+// runChangeCommand は、run コマンドを実行します。-json が指定された場合は、実行結果を JSON 形式で出力します。
+func runChangeCommand(ctx *commandContext, reindex bool) error {
+	start := time.Now()
+	var result runResult
+	err := runChange(ctx, reindex, &result)
+	if !ctx.JSON {
+		return err
+	}
+
+	result.finish(start, err)
+	ctx.jsonWritten = true
+	if writeErr := writeJSON(ctx.Out, result); writeErr != nil && err == nil {
+		return writeErr
+	}
+	return err
+}
+
+// runChange は、画像を選択し、スプラッシュスクリーンを変更します。選択した画像や保存先は result に設定します。
+// 返すエラーには、失敗の原因に応じた終了コードを付けます。
+func runChange(ctx *commandContext, reindex bool, result *runResult) error {
+	config := ctx.Config
+	sources, err := resolveSourcesVerbose(config)
+	if err != nil {
+		return withExitCode(exitNoSource, err)
+	}
+	destinationPath, err := getDestinationPathVerbose(config)
+	if err != nil {
+		return withExitCode(exitNoDestination, err)
+	}
+	destFile := splashScreenFile(destinationPath)
+	result.Destination = destFile
+	result.Width = config.Destination.Width
+	result.Height = config.Destination.Height
+
+	// 設定値を表示する
+	for _, source := range sources {
+		slog.Info("Source", "path", source.Path, "recursive", source.Recursive, "weight", source.Weight)
+	}
+	slog.Info("Source settings", "max_pixels", config.Source.MaxPixels, "orientation", config.Source.Orientation)
+	slog.Info("Destination", "path", destinationPath, "width", config.Destination.Width, "height", config.Destination.Height)
+
+	index := openLibraryIndex(reindex)
+	defer saveLibraryIndex(index)
+
+	state, err := loadState(getDataFilePath("state.json"))
+	if err != nil {
+		slog.Warn("Failed to load state file, starting a new history", "error", err)
+	}
+
+	var pickedAt time.Time
+	pickedFile, selectErr, resizeErr := pickAndResize(config, sources, index, state, reindex, func(pickedFile string) error {
+		// 元の画像のパスや選択日時を、生成する画像のメタデータとして書き込む
+		pickedAt = time.Now()
+		texts, err := splashScreenTexts(pickedFile, pickedAt, config.Destination.CopyMetadata)
+		if err != nil {
+			return err
+		}
+
+		// 元のスプラッシュスクリーンを restore コマンドで戻せるよう、変更する前にバックアップする
+		if err := backupSplashScreen(destFile, getDataFilePath(splashScreenBackupName)); err != nil {
+			return fmt.Errorf("failed to back up the original splash screen: %w", err)
+		}
+
+		// ファイルをリサイズして EasyAntiCheat ディレクトリに保存する
+		return resizePNGFile(pickedFile, destFile, config.Destination.Width, config.Destination.Height, config.Source.MaxPixels, texts...)
+	})
+	if errors.Is(selectErr, errNoPNGFiles) {
+		return withExitCode(exitNoFiles, selectErr)
+	}
+	if selectErr != nil {
+		// ソースフォルダや設定値の問題は selectImage が付けた終了コードを使用し、それ以外は一般的なエラーとする
+		var exitErr *exitError
+		if !errors.As(selectErr, &exitErr) {
+			selectErr = withExitCode(exitFailure, selectErr)
+		}
+		return selectErr
+	}
+	source, err := filepath.Abs(pickedFile)
+	if err != nil {
+		slog.Warn("Failed to get the absolute path of the picked file", "path", pickedFile, "error", err)
+		source = pickedFile
+	}
+	result.Source = source
+	if entry, err := index.entry(pickedFile); err == nil {
+		result.SourceWidth = entry.Width
+		result.SourceHeight = entry.Height
+	}
+	if resizeErr != nil {
+		return withExitCode(exitWriteFailed, resizeErr)
+	}
+	slog.Info("Resized file saved", "path", destFile)
+
+	// 選択履歴を状態ファイルに保存する
+	state.recordPick(pickRecord{Source: source, Destination: destFile, PickedAt: pickedAt})
+	if err := state.save(); err != nil {
+		slog.Warn("Failed to save state file", "error", err)
+	}
+	return nil
+}
+
+// runDaemon は、daemon コマンドを実行します。
+// 終了するまで interval ごとにスプラッシュスクリーンを変更し、設定ファイルが変更された場合は再起動せずに読み込み直します。
+// 読み込み直した設定に問題がある場合は、前の設定を使い続けます。ログの設定（log.*）の変更は、再起動するまで反映されません。
+func runDaemon(ctx *commandContext, interval time.Duration) error {
+	if interval <= 0 {
+		return withExitCode(exitUsage, fmt.Errorf("interval must be greater than 0, got %s", interval))
+	}
+
+	signalCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	watcher := newConfigWatcher(ctx.ConfigPath, ctx.Loaded, ctx.ReloadConfig)
+	go func() {
+		if err := watcher.watch(signalCtx); err != nil {
+			slog.Error("Failed to watch the configuration file, changes are applied after a restart", "error", err)
+		}
+	}()
+
+	slog.Info("Running as a daemon", "interval", interval.String())
+	for {
+		// 日付が変わった場合は、新しい日付のログファイルに切り替え、古いログファイルを削除する
+		if ctx.LogFile != nil {
+			ctx.LogFile.refresh()
+		}
+
+		// 変更ごとに、その時点で有効な設定を使用する
+		loaded := watcher.Loaded()
+		runCtx := *ctx
+		runCtx.Config, runCtx.Loaded = loaded.Config, loaded
+		var result runResult
+		if err := runChange(&runCtx, false, &result); err != nil {
+			slog.Error("Failed to change the splash screen", "error", err, "exit_code", exitCodeOf(err))
+		}
+
+		select {
+		case <-signalCtx.Done():
+			slog.Info("Stopping the daemon")
+			return nil
+		case <-time.After(interval):
+		}
+	}
+}
+
+// runPreview は、preview コマンドを実行します。
+// run コマンドと同じように画像を選択して指定されたファイルに保存しますが、スプラッシュスクリーンや状態ファイルは変更しません。
+func runPreview(ctx *commandContext, output string) error {
+	config := ctx.Config
+	sources, err := resolveSourcesVerbose(config)
+	if err != nil {
+		return err
+	}
+
+	index := openLibraryIndex(false)
+	defer saveLibraryIndex(index)
+
+	// 状態ファイルは保存しないため、pick コマンドの画像はプレビューしても次回の実行で使用される
+	state, err := loadState(getDataFilePath("state.json"))
+	if err != nil {
+		slog.Warn("Failed to load state file", "error", err)
+	}
+
+	pickedFile, selectErr, resizeErr := pickAndResize(config, sources, index, state, false, func(pickedFile string) error {
+		texts, err := splashScreenTexts(pickedFile, time.Now(), config.Destination.CopyMetadata)
+		if err != nil {
+			return err
+		}
+		return resizePNGFile(pickedFile, output, config.Destination.Width, config.Destination.Height, config.Source.MaxPixels, texts...)
+	})
+	if selectErr != nil {
+		return selectErr
+	}
+	if resizeErr != nil {
+		return resizeErr
+	}
+
+	fmt.Fprintf(ctx.Out, "Source: %s\n", pickedFile)
+	fmt.Fprintf(ctx.Out, "Preview: %s\n", output)
+	return nil
+}
+
+// runList は、list コマンドを実行します。絞り込み条件を満たすソース画像を表示します。
+func runList(ctx *commandContext) error {
+	sources, err := resolveSourcesVerbose(ctx.Config)
+	if err != nil {
+		return err
+	}
+
+	index := openLibraryIndex(false)
+	defer saveLibraryIndex(index)
+
+	candidates, err := listCandidates(sources, index)
+	if err != nil {
+		return err
+	}
+	candidates, err = filterCandidates(ctx.Config, candidates, sources, index)
+	if err != nil {
+		return err
+	}
+
+	paths := candidatePaths(candidates)
+	if ctx.JSON {
+		return writeJSON(ctx.Out, paths)
+	}
+	for _, path := range paths {
+		fmt.Fprintln(ctx.Out, path)
+	}
+	return nil
+}
+
+// runCurrent は、current コマンドを実行します。現在のスプラッシュスクリーンの元の画像を表示します。
+func runCurrent(ctx *commandContext) error {
+	state, err := loadState(getDataFilePath("state.json"))
+	if err != nil {
+		slog.Warn("Failed to load state file", "error", err)
+	}
+
+	// 保存先を取得できない場合は、状態ファイルの最後の選択を表示する
+	destFile := ""
+	if destinationPath, err := getDestinationPath(ctx.Config); err == nil {
+		destFile = splashScreenFile(destinationPath)
+	}
+	info, err := resolveCurrent(destFile, state)
+	if err != nil {
+		return err
+	}
+
+	if ctx.JSON {
+		return writeJSON(ctx.Out, info)
+	}
+	printSplashScreenInfo(ctx.Out, info)
+	return nil
+}
+
+// runHistory は、history コマンドを実行します。
+func runHistory(ctx *commandContext) error {
+	state, err := loadState(getDataFilePath("state.json"))
+	if err != nil {
+		return fmt.Errorf("failed to load state file: %w", err)
+	}
+
+	if ctx.JSON {
+		return writeJSON(ctx.Out, state.History)
+	}
+	printHistory(ctx.Out, state.History)
+	return nil
+}
+
+// runRestore は、restore コマンドを実行します。バックアップした元のスプラッシュスクリーンに戻します。
+func runRestore(ctx *commandContext) error {
+	destinationPath, err := getDestinationPathVerbose(ctx.Config)
+	if err != nil {
+		return err
+	}
+
+	destFile := splashScreenFile(destinationPath)
+	if err := restoreSplashScreen(getDataFilePath(splashScreenBackupName), destFile); err != nil {
+		return err
+	}
+	slog.Info("Restored the original splash screen", "path", destFile)
+	return nil
+}
+
+// runTag は、tag コマンドを実行します。
+func runTag(ctx *commandContext) error {
+	if len(ctx.Args) == 0 {
+		return errUsage
+	}
+
+	index, err := loadLibraryIndex(getDataFilePath("library.json"))
+	if err != nil {
+		return fmt.Errorf("failed to load library index: %w", err)
+	}
+	if err := runTagCommand(ctx.Args[0], ctx.Args[1:], index, ctx.Out); err != nil {
+		return err
+	}
+	if err := index.save(); err != nil {
+		return fmt.Errorf("failed to save library index: %w", err)
+	}
+	return nil
+}
+
+// runDuplicates は、duplicates コマンドを実行します。似た画像のグループを表示します。
+func runDuplicates(ctx *commandContext) error {
+	sources, err := resolveSourcesVerbose(ctx.Config)
+	if err != nil {
+		return err
+	}
+
+	index := openLibraryIndex(false)
+	defer saveLibraryIndex(index)
+
+	candidates, err := listCandidates(sources, index)
+	if err != nil {
+		return err
+	}
+	groups := groupDuplicates(candidates, index, ctx.Config.Selection.DuplicateThreshold)
+	printDuplicateGroups(ctx.Out, groups)
+	return nil
+}
+
+// runConfigValidate は、config validate コマンドを実行します。
+// 設定値のチェックは設定ファイルの読み込み時に行われるため、ここではソースフォルダと保存先のフォルダを取得できるかを確認します。
+func runConfigValidate(ctx *commandContext) error {
+	// 設定ファイルの内容の問題は、設定ファイルの読み込み時に表示される。ここでは、自動で検出するフォルダの問題をまとめて表示する
+	var problems []configProblem
+	if _, err := resolveSources(ctx.Config); err != nil {
+		problems = append(problems, newConfigProblem("source.path", err))
+	}
+	if _, err := getDestinationPath(ctx.Config); err != nil {
+		problems = append(problems, newConfigProblem("destination.path", err))
+	}
+	if err := newConfigError(ctx.ConfigPath, problems); err != nil {
+		var configErr *configError
+		if errors.As(err, &configErr) && !ctx.JSON {
+			printConfigProblems(ctx.Out, configErr)
+		}
+		var exitErr *exitError
+		if !errors.As(err, &exitErr) {
+			err = withExitCode(exitConfigError, err)
+		}
+		return err
+	}
+	switch len(ctx.Loaded.Warnings) {
+	case 0:
+		fmt.Fprintf(ctx.Out, "Configuration is valid: %s\n", ctx.ConfigPath)
+	case 1:
+		fmt.Fprintf(ctx.Out, "Configuration is valid with 1 warning: %s\n", ctx.ConfigPath)
+	default:
+		fmt.Fprintf(ctx.Out, "Configuration is valid with %d warnings: %s\n", len(ctx.Loaded.Warnings), ctx.ConfigPath)
+	}
+	return nil
+}
+
+// configPathResult は、config path コマンドの実行結果です。
+type configPathResult struct {
+	Path     string            `json:"path"`
+	Reason   string            `json:"reason"`
+	Exists   bool              `json:"exists"`
+	Searched []configCandidate `json:"searched"`
+}
+
+// runConfigPath は、config path コマンドを実行します。使用する設定ファイルと、その理由、探した場所の一覧を表示します。
+func runConfigPath(ctx *commandContext, flagValue string) error {
+	found, candidates := findConfigFile(flagValue)
+	_, err := os.Stat(found.Path)
+	result := configPathResult{Path: found.Path, Reason: found.Reason, Exists: err == nil, Searched: candidates}
+	if ctx.JSON {
+		return writeJSON(ctx.Out, result)
+	}
+
+	fmt.Fprintf(ctx.Out, "Configuration file: %s\n", result.Path)
+	fmt.Fprintf(ctx.Out, "Reason: %s\n", result.Reason)
+	if !result.Exists {
+		fmt.Fprintln(ctx.Out, "The file does not exist. Only environment variables and default values are used.")
+	}
+	fmt.Fprintln(ctx.Out)
+	fmt.Fprintln(ctx.Out, "Searched locations, in order of priority:")
+	for _, candidate := range candidates {
+		mark := " "
+		switch {
+		case candidate.Path == found.Path && candidate.Reason == found.Reason:
+			mark = "*"
+		case candidate.Exists:
+			mark = "+"
+		}
+		fmt.Fprintf(ctx.Out, "  %s %s (%s)\n", mark, candidate.Path, candidate.Reason)
+	}
+	fmt.Fprintln(ctx.Out)
+	fmt.Fprintln(ctx.Out, "* used, + exists but has lower priority")
+	return nil
+}
+
+// runConfigShow は、config show コマンドを実行します。
+// effective が false の場合は、設定ファイルをマージした内容を表示します。
+// true の場合は、環境変数とデフォルト値を含めた最終的な設定値を、各値を指定した場所のコメント付きで表示します。
+func runConfigShow(ctx *commandContext, effective bool) error {
+	loaded := ctx.Loaded
+	if len(loaded.Files) == 0 {
+		fmt.Fprintf(ctx.Out, "# No configuration file was loaded: %s\n", ctx.ConfigPath)
+	}
+	for _, file := range loaded.Files {
+		fmt.Fprintf(ctx.Out, "# %s\n", file)
+	}
+
+	root := loaded.Merged
+	if effective {
+		root = &yaml.Node{}
+		if err := root.Encode(loaded.Config); err != nil {
+			return err
+		}
+		annotateOrigins(root, "", loaded.Origins)
+	}
+	if root.Kind == 0 {
+		return nil
+	}
+
+	encoder := yaml.NewEncoder(ctx.Out)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(root); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
+
+// annotateOrigins は、設定値の各項目に、値を指定した場所を行末のコメントとして付けます。
+// 値が空でないリストの場合はキーに、それ以外の場合は値にコメントを付けます（空のリスト [] のキーに付けると、次の行に出力されるため）。
+func annotateOrigins(node *yaml.Node, prefix string, origins map[string]string) {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		for _, child := range node.Content {
+			annotateOrigins(child, prefix, origins)
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := node.Content[i].Value
+			if prefix != "" {
+				key = prefix + "." + key
+			}
+			if origin, ok := origins[key]; ok {
+				if value := node.Content[i+1]; len(value.Content) == 0 {
+					value.LineComment = origin
+				} else {
+					node.Content[i].LineComment = origin
+				}
+				continue
+			}
+			annotateOrigins(node.Content[i+1], key, origins)
+		}
+	}
+}
+
+// runConfigSchema は、config schema コマンドを実行します。
+// output が空の場合は標準出力に出力します。PowerShell のリダイレクトは UTF-16 で書き込むため、ファイルに保存する場合は output を指定します。
+func runConfigSchema(ctx *commandContext, output string) error {
+	if output == "" {
+		return writeJSON(ctx.Out, generateConfigSchema())
+	}
+	var buf bytes.Buffer
+	if err := writeJSON(&buf, generateConfigSchema()); err != nil {
+		return err
+	}
+	if err := os.WriteFile(output, buf.Bytes(), 0644); err != nil {
+		return err
+	}
+	fmt.Fprintf(ctx.Out, "Wrote the configuration schema to %s\n", output)
+	return nil
+}
+
+// runConfigInit は、config init コマンドを実行します。
+// ソースフォルダと保存先のフォルダを自動で検出して既定値とし、interactive が true の場合は設定値を対話形式で尋ねてから、設定ファイルを作成します。
+// 検出できなかったフォルダは空のままにし、実行時に検出します。
+func runConfigInit(ctx *commandContext, path string, force, interactive bool) error {
+	// 作成する設定ファイルは YAML のみに対応する
+	if format := detectConfigFormat(path, nil); format != formatYAML {
+		return withExitCode(exitUsage, fmt.Errorf("config init creates YAML files only, but %s is a %s file name", path, format))
+	}
+	if _, err := os.Stat(path); err == nil && !force {
+		return fmt.Errorf("configuration file %s already exists (use -force to overwrite)", path)
+	}
+
+	var config Config
+	setDefaults(&config, nil)
+	if sourcePath, err := getSourcePath(&config); err == nil {
+		config.Source.Path = sourcePath
+	} else if !interactive {
+		slog.Warn("Could not detect the source path, please set source.path")
+	}
+	if destinationPath, err := getDestinationPath(&config); err == nil {
+		config.Destination.Path = destinationPath
+	} else if !interactive {
+		slog.Warn("Could not detect the destination path, please set destination.path")
+	}
+
+	if interactive {
+		newConfigWizard(ctx.In, ctx.Out).run(&config)
+	}
+
+	data, err := marshalCommentedConfig(&config)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return err
+	}
+	fmt.Fprintf(ctx.Out, "Created configuration file: %s\n", path)
+	return nil
+}
+
+// バックアップした元のスプラッシュスクリーンのファイル名（データフォルダからの相対パス）
+var splashScreenBackupName = filepath.Join("backup", "SplashScreen.png")
+
+// backupSplashScreen は、スプラッシュスクリーンファイルがこのアプリケーションで生成したものでない場合に、バックアップします。
+// VRChat の更新などでスプラッシュスクリーンが元に戻った場合は、その画像でバックアップを更新します。
+func backupSplashScreen(destFile, backupPath string) error {
+	if _, err := os.Stat(destFile); errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if _, err := readSplashScreenInfo(destFile); err == nil {
+		return nil
+	}
+
+	slog.Info("Backing up the original splash screen", "path", backupPath)
+	return copyFile(destFile, backupPath)
+}
+
+// restoreSplashScreen は、バックアップした元のスプラッシュスクリーンを戻します。
+func restoreSplashScreen(backupPath, destFile string) error {
+	if _, err := os.Stat(backupPath); errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("no backup of the original splash screen found at %s", backupPath)
+	}
+	return copyFile(backupPath, destFile)
+}
+
+// copyFile は、ファイルをコピーします。コピー先のフォルダが存在しない場合は作成します。
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	if err := os.MkdirAll(filepath.Dir(dst), os.ModePerm); err != nil {
+		return err
+	}
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
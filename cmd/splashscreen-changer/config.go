@@ -2,9 +2,11 @@ package main
 
 import (
 	"fmt"
+	"io"
+	"log/slog"
 	"os"
+	"path/filepath"
 	"reflect"
-	"strconv"
 	"strings"
 
 	"gopkg.in/yaml.v3"
@@ -12,44 +14,196 @@ import (
 
 type Config struct {
 	Source struct {
-		Path      string `yaml:"path" help:"Path to the source directory. If not specified, the VRChat folder in the user's Pictures folder is searched and used if available. If not, an error is returned."`
-		Recursive bool   `yaml:"recursive" help:"Whether to search for PNG files recursively" default:"true"`
+		Path        string       `yaml:"path" help:"Path to the source directory. If not specified, the VRChat folder in the user's Pictures folder is searched and used if available. If not, an error is returned."`
+		Recursive   bool         `yaml:"recursive" help:"Whether to search for PNG files recursively" default:"true"`
+		MaxPixels   int          `yaml:"max_pixels" help:"Maximum number of pixels (width x height) of a source image. Larger images are skipped without decoding and another image is picked" default:"100000000"`
+		MinWidth    int          `yaml:"min_width" help:"Minimum width of source images. Narrower images are not picked"`
+		MinHeight   int          `yaml:"min_height" help:"Minimum height of source images. Shorter images are not picked"`
+		MinAspect   float64      `yaml:"min_aspect" help:"Minimum aspect ratio (width / height) of source images"`
+		MaxAspect   float64      `yaml:"max_aspect" help:"Maximum aspect ratio (width / height) of source images"`
+		Orientation string       `yaml:"orientation" help:"Orientation of source images to pick (landscape, portrait or any)" default:"any"`
+		Include     []string     `yaml:"include" help:"Glob patterns (relative to the source directory, ** matches any number of folders) of files to pick. Comma-separated in environment variables"`
+		Exclude     []string     `yaml:"exclude" help:"Glob patterns of files and folders to skip. Excluded folders are not searched. Comma-separated in environment variables"`
+		SkipHidden  bool         `yaml:"skip_hidden" help:"Whether to skip hidden files and folders"`
+		Paths       []SourcePath `yaml:"paths" env:"-" help:"List of source directories, each with optional recursive, include, exclude and weight settings. Used instead of source.path when specified"`
 	} `yaml:"source" required:"true"`
 	Destination struct {
-		Path   string `yaml:"path" help:"Path to the destination directory. The specified directory must have an EasyAntiCheat directory. If not specified, the VRChat folder is searched based on the Steam library folder and used if available. If not, an error is returned."`
-		Width  int    `yaml:"width" help:"Width of the destination image" default:"800"`
-		Height int    `yaml:"height" help:"Height of the destination image" default:"450"`
+		Path         string `yaml:"path" help:"Path to the destination directory. The specified directory must have an EasyAntiCheat directory. If not specified, the VRChat folder is searched based on the Steam library folder and used if available. If not, an error is returned."`
+		Width        int    `yaml:"width" help:"Width of the destination image" default:"800"`
+		Height       int    `yaml:"height" help:"Height of the destination image" default:"450"`
+		CopyMetadata bool   `yaml:"copy_metadata" help:"Whether to copy metadata such as the author and XMP of the source image to the destination image"`
 	} `yaml:"destination" required:"true"`
+	Selection struct {
+		Deduplicate        bool `yaml:"deduplicate" help:"Whether to treat near-duplicate images (such as burst shots) as a single image when picking"`
+		DuplicateThreshold int  `yaml:"duplicate_threshold" help:"Maximum difference (0-64) between perceptual hashes of images regarded as near-duplicates" default:"10"`
+	} `yaml:"selection"`
+	Tags struct {
+		Include     []string `yaml:"include" help:"Tag expressions of images to pick. Images matching any of them are picked. Join tags with + to require all of them (e.g. sunset+group). Comma-separated in environment variables"`
+		Exclude     []string `yaml:"exclude" help:"Tag expressions of images to skip. Comma-separated in environment variables"`
+		FromFolders bool     `yaml:"from_folders" help:"Whether to use the names of the folders containing an image (below its source directory) as its tags"`
+	} `yaml:"tags"`
+	Screenshot struct {
+		Worlds     []string `yaml:"worlds" help:"World IDs or names of VRChat screenshots to pick. Comma-separated in environment variables"`
+		Players    []string `yaml:"players" help:"User IDs or display names of players. Only screenshots taken by or with any of them are picked. Comma-separated in environment variables"`
+		MaxAgeDays int      `yaml:"max_age_days" help:"Only pick screenshots taken within this many days. 0 means no limit"`
+	} `yaml:"screenshot"`
 	Log struct {
-		Path string `yaml:"path" help:"Path to the log file" default:""`
+		Path       string `yaml:"path" help:"Path to the log file, or the directory to create a log file per day in" default:""`
+		Level      string `yaml:"level" help:"Minimum level of log messages (debug, info, warn or error)" default:"info"`
+		Format     string `yaml:"format" help:"Format of log messages (text or json)" default:"text"`
+		MaxAgeDays int    `yaml:"max_age_days" help:"Delete log files older than this many days. 0 keeps them forever" default:"30"`
+		MaxSizeMB  int    `yaml:"max_size_mb" help:"Rotate the log file when it grows larger than this many megabytes. 0 means no limit" default:"10"`
 	} `yaml:"log"`
 }
 
+// explicitFields は、設定ファイル・環境変数・コマンドライン引数で明示的に指定された項目の集合です。
+// キーは、"source.recursive" のように yaml タグの名前をピリオドで連結したものです。
+// false・0・空文字列が指定された項目も含むため、デフォルト値で上書きしないよう判別できます。
+type explicitFields map[string]bool
+
 // 設定ファイルを読み込む
+// 設定値に問題がある場合は、すべての問題を設定ファイルでの位置とともに *configError として返す
+// 設定ファイルの不明なキーは無視する。不明なキーを警告として取得する場合は loadConfig を使用する
 func LoadConfig(filename string) (*Config, error) {
+	loaded, err := loadConfig(filename, false, nil)
+	if loaded == nil {
+		return nil, err
+	}
+	return loaded.Config, err
+}
+
+// loadedConfig は、設定ファイルの読み込み結果です。
+type loadedConfig struct {
+	Config *Config
+	// Warnings は、設定ファイルの不明なキーなどの警告
+	Warnings []configProblem
+	// Files は、読み込んだ設定ファイル（include で指定したファイル、config.local.yml を含む）。マージした順
+	Files []string
+	// Merged は、設定ファイルをマージした内容。環境変数とデフォルト値は含まない
+	Merged *yaml.Node
+	// Origins は、各設定項目のキーと、値を指定した場所（"config.yml:3"、"DESTINATION_WIDTH (environment variable)"、"--destination.width (command-line flag)"、"default"）
+	Origins map[string]string
+}
+
+// loadConfig は、設定ファイルを読み込み、設定値と警告を返します。
+// include で指定したファイル、config.local.yml の順に設定ファイルを重ね、その後に環境変数、コマンドライン引数の値（flagValues）の順に上書きします。
+// 設定ファイルの不明なキー（例: destinaton、recusive）は、strict が false の場合は警告とし、true の場合は問題とします。
+// 設定値に問題がある場合も、読み込めた設定値を返します。
+func loadConfig(filename string, strict bool, flagValues map[string][]string) (*loadedConfig, error) {
 	var config Config
+	var problems, warnings []configProblem
+	explicit := explicitFields{}
 
 	// 設定ファイルが存在する場合のみ読み込む
-	if _, err := os.Stat(filename); err == nil {
-		data, err := os.ReadFile(filename)
-		if err != nil {
-			return nil, err
+	// YAML 以外の形式も yaml.Node に変換し、以降は同じ方法で読み込む
+	layers, layerProblems, err := loadConfigLayers(filename)
+	if err != nil {
+		return nil, err
+	}
+	// 構文エラーや include の問題がある場合は、以降の項目をチェックできない
+	if len(layerProblems) > 0 {
+		return nil, newConfigError(filename, layerProblems)
+	}
+
+	var files []string
+	for _, layer := range layers {
+		files = append(files, layer.Path)
+		if layer.Root.Kind == 0 {
+			continue
 		}
-		err = yaml.Unmarshal(data, &config)
-		if err != nil {
-			return nil, err
+		// 型のエラーは、マージする前に設定ファイルごとにチェックし、行番号から項目を特定する
+		var scratch Config
+		if err := layer.Root.Decode(&scratch); err != nil {
+			typeProblems := yamlErrorProblems(err, formatYAML)
+			for i := range typeProblems {
+				typeProblems[i].File = layer.Path
+			}
+			locateProblems(typeProblems, yamlPositions(layer.Root), nil, nil, nil)
+			problems = append(problems, typeProblems...)
+		}
+	}
+
+	root, fileOrigins := mergeConfigLayers(layers)
+	positions := yamlPositions(root)
+	if root.Kind != 0 {
+		// 型のエラーがあっても、ほかの項目は読み込まれる。型のエラーは設定ファイルごとにチェック済み
+		_ = root.Decode(&config)
+		for key := range yamlExplicitFields(root) {
+			explicit[key] = true
+		}
+		if unknown := unknownKeyProblems(root, reflect.TypeOf(config)); strict {
+			problems = append(problems, unknown...)
+		} else {
+			locateProblems(unknown, positions, nil, nil, fileOrigins)
+			warnings = unknown
 		}
 	}
 
 	// 環境変数で設定を上書き
-	overrideConfigWithEnv(&config)
+	envFields, envProblems := overrideConfigWithEnv(&config)
+	for key := range envFields {
+		explicit[key] = true
+	}
+	problems = append(problems, envProblems...)
+
+	// コマンドライン引数で設定を上書き
+	flagFields, flagProblems := overrideConfigWithFlags(&config, flagValues)
+	for key := range flagFields {
+		explicit[key] = true
+	}
+	problems = append(problems, flagProblems...)
 
-	// デフォルト値を設定
-	setDefaults(&config)
+	// 明示的に指定されていない項目にデフォルト値を設定
+	setDefaults(&config, explicit)
 
 	// 設定ファイルの内容をチェック
-	err := checkConfig(&config)
-	return &config, err
+	// 読み込みに失敗した項目は、既に問題として報告しているためチェックの結果を報告しない
+	locateProblems(problems, positions, flagFields, envFields, fileOrigins)
+	reported := map[string]bool{}
+	for _, problem := range problems {
+		reported[problem.Field] = true
+	}
+	for _, problem := range validateConfig(&config) {
+		if !reported[problem.Field] {
+			problems = append(problems, problem)
+		}
+	}
+	locateProblems(problems, positions, flagFields, envFields, fileOrigins)
+
+	loaded := &loadedConfig{
+		Config:   &config,
+		Warnings: warnings,
+		Files:    files,
+		Merged:   root,
+		Origins:  valueOrigins(&config, positions, flagFields, envFields, fileOrigins),
+	}
+	return loaded, newConfigError(filename, problems)
+}
+
+// valueOrigins は、各設定項目の値を指定した場所を返します。
+// コマンドライン引数・環境変数・設定ファイル（パスと行番号）・デフォルト値の順に調べ、いずれでもない場合は "not set" とします。
+func valueOrigins(config *Config, positions map[string]*yaml.Node, flagFields, envFields explicitFields, fileOrigins map[string]string) map[string]string {
+	origins := map[string]string{}
+	walkConfigFields(config, func(field configField) {
+		switch {
+		case flagFields[field.Key]:
+			origins[field.Key] = "--" + field.Key + " (command-line flag)"
+		case envFields[field.Key]:
+			origins[field.Key] = field.EnvKey + " (environment variable)"
+		case fileOrigins[field.Key] != "":
+			origins[field.Key] = fileOrigins[field.Key]
+			if node, ok := positions[field.Key]; ok {
+				origins[field.Key] += fmt.Sprintf(":%d", node.Line)
+			}
+		default:
+			if _, ok := field.Field.Tag.Lookup("default"); ok {
+				origins[field.Key] = "default"
+			} else {
+				origins[field.Key] = "not set"
+			}
+		}
+	})
+	return origins
 }
 
 // 環境変数で設定を動的に取得する
@@ -60,139 +214,229 @@ func getEnv(key string, defaultValue string) string {
 	return defaultValue
 }
 
-// 環境変数で設定を動的に取得する
-func overrideConfigWithEnv(config *Config) {
-	configValue := reflect.ValueOf(config).Elem()
-	configType := configValue.Type()
-
-	for i := 0; i < configValue.NumField(); i++ {
-		section := configValue.Field(i)
-		sectionType := section.Type()
-
-		for j := 0; j < section.NumField(); j++ {
-			field := section.Field(j)
-			fieldType := sectionType.Field(j)
-			envKey := strings.ToUpper(configType.Field(i).Name + "_" + fieldType.Name)
-
-			if value := getEnv(envKey, ""); value != "" {
-				switch field.Kind() {
-				case reflect.String:
-					field.SetString(value)
-				case reflect.Bool:
-					if boolValue, err := strconv.ParseBool(value); err == nil {
-						field.SetBool(boolValue)
-					} else {
-						fmt.Printf("Error parsing bool for %s: %v\n", envKey, err)
-					}
-				case reflect.Int:
-					if intValue, err := strconv.Atoi(value); err == nil {
-						field.SetInt(int64(intValue))
-					} else {
-						fmt.Printf("Error parsing int for %s: %v\n", envKey, err)
-					}
-				}
-			}
+// フィールドの yaml タグから名前を取得する。タグがない場合はフィールド名を使用する
+func getYAMLName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+	if name == "" {
+		return field.Name
+	}
+	return name
+}
+
+// カンマ区切りの文字列をリストに変換する。各要素の前後の空白と、空の要素は取り除く
+func splitList(value string) []string {
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
 		}
 	}
+	return list
 }
 
-// デフォルト値を設定する
-func setDefaults(config *Config) {
-	configValue := reflect.ValueOf(config).Elem()
-
-	for i := 0; i < configValue.NumField(); i++ {
-		section := configValue.Field(i)
-		sectionType := section.Type()
-
-		for j := 0; j < section.NumField(); j++ {
-			field := section.Field(j)
-			fieldType := sectionType.Field(j)
-
-			if defaultValue, exists := fieldType.Tag.Lookup("default"); exists {
-				switch field.Kind() {
-				case reflect.String:
-					if field.String() == "" {
-						field.SetString(defaultValue)
-					}
-				case reflect.Bool:
-					if !field.Bool() {
-						boolValue, _ := strconv.ParseBool(defaultValue)
-						field.SetBool(boolValue)
-					}
-				case reflect.Int:
-					if field.Int() == 0 {
-						intValue, _ := strconv.Atoi(defaultValue)
-						field.SetInt(int64(intValue))
-					}
-				}
+// yamlExplicitFields は、設定ファイルで値が指定された項目を返します。値が空（null）の項目は含みません。
+// 最上位のセクション（例: source）自体は含まず、中の項目（例: source.recursive）を含みます。さらに入れ子になったセクションやマップは、その項目自体と中の項目の両方を含みます。
+func yamlExplicitFields(root *yaml.Node) explicitFields {
+	fields := explicitFields{}
+	var walk func(node *yaml.Node, prefix string)
+	walk = func(node *yaml.Node, prefix string) {
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			value := node.Content[i+1]
+			if value.Tag == "!!null" {
+				continue
+			}
+			key := node.Content[i].Value
+			if prefix != "" {
+				key = prefix + "." + key
+				fields[key] = true
+			}
+			if value.Kind == yaml.MappingNode {
+				walk(value, key)
 			}
 		}
 	}
+	if root.Kind == yaml.DocumentNode && len(root.Content) > 0 && root.Content[0].Kind == yaml.MappingNode {
+		walk(root.Content[0], "")
+	}
+	return fields
+}
+
+// 環境変数で設定を動的に取得する。環境変数で指定された項目と、値を解析できなかった環境変数の問題を返す
+// 環境変数のキーは、yaml タグの名前をアンダースコアで連結し、大文字にしたものとする（例: source.max_pixels → SOURCE_MAX_PIXELS）
+// リストはカンマ区切り、マップは key=value のカンマ区切りで指定する。空の環境変数は、指定されていないものとして扱う
+func overrideConfigWithEnv(config any) (explicitFields, []configProblem) {
+	explicit := explicitFields{}
+	var problems []configProblem
+
+	walkConfigFields(config, func(field configField) {
+		// env:"-" が付いたフィールドは、環境変数で上書きしない
+		if field.EnvKey == "" {
+			return
+		}
+		value := getEnv(field.EnvKey, "")
+		if value == "" {
+			return
+		}
+		if err := setFieldFromString(field.Value, value); err != nil {
+			problems = append(problems, configProblem{Field: field.Key, Env: field.EnvKey, Message: err.Error(), err: err})
+			return
+		}
+		explicit[field.Key] = true
+	})
+	return explicit, problems
+}
+
+// デフォルト値を設定する
+// explicit に含まれる項目は、false・0・空文字列であっても指定された値のままにする。explicit が nil の場合は、すべての項目に設定する
+func setDefaults(config any, explicit explicitFields) {
+	walkConfigFields(config, func(field configField) {
+		if explicit[field.Key] {
+			return
+		}
+		if defaultValue, exists := field.Field.Tag.Lookup("default"); exists {
+			// default タグの書式はテストでチェックする
+			_ = setFieldFromString(field.Value, defaultValue)
+		}
+	})
+}
+
+// requiredProblems は、required タグが付いている項目のうち、空の値が設定されている項目の問題を返します。
+func requiredProblems(config any) []configProblem {
+	var problems []configProblem
+	walkConfigFields(config, func(field configField) {
+		if required, _ := field.Field.Tag.Lookup("required"); required != "true" || !field.Value.IsZero() {
+			return
+		}
+		if field.Value.Kind() == reflect.String {
+			problems = append(problems, newConfigProblem(field.Key, fmt.Errorf("%s is required but was empty", field.Key)))
+		} else {
+			problems = append(problems, newConfigProblem(field.Key, fmt.Errorf("%s is required", field.Key)))
+		}
+	})
+	return problems
 }
 
 // 設定ファイルの内容をチェックする
+// 問題がある場合は、すべての問題を *configError として返す
 func checkConfig(config *Config) error {
-	// 各フィールドが空でないかチェック
-	configValue := reflect.ValueOf(config).Elem()
-	configType := configValue.Type()
-
-	for i := 0; i < configValue.NumField(); i++ {
-		section := configValue.Field(i)
-		sectionType := section.Type()
-
-		for j := 0; j < section.NumField(); j++ {
-			field := section.Field(j)
-			fieldType := sectionType.Field(j)
-			fieldName := configType.Field(i).Name + "." + fieldType.Name
-
-			// required タグが付いている場合、空の値が設定されていないかチェック
-			if required, _ := fieldType.Tag.Lookup("required"); required == "true" {
-				switch field.Kind() {
-				case reflect.String:
-					if field.String() == "" {
-						return fmt.Errorf("%s is required but was empty", strings.ToLower(fieldName))
-					}
-				case reflect.Bool:
-					if !field.Bool() {
-						return fmt.Errorf("%s is required", strings.ToLower(fieldName))
-					}
-				case reflect.Int:
-					if field.Int() == 0 {
-						return fmt.Errorf("%s is required", strings.ToLower(fieldName))
-					}
-				}
-			}
-		}
+	return newConfigError("", validateConfig(config))
+}
+
+// 設定ファイルの内容をチェックし、すべての問題を返す
+func validateConfig(config *Config) []configProblem {
+	var problems []configProblem
+	add := func(field string, err error) {
+		problems = append(problems, newConfigProblem(field, err))
 	}
 
+	// required タグが付いている項目が空でないかチェック
+	problems = append(problems, requiredProblems(config)...)
+
 	// パスが存在するかチェック
 	if config.Source.Path != "" {
 		if _, err := os.Stat(config.Source.Path); err != nil {
-			return fmt.Errorf("source path '%s' does not exist", config.Source.Path)
+			add("source.path", withExitCode(exitNoSource, fmt.Errorf("source path '%s' does not exist", config.Source.Path)))
 		}
 	}
 
 	if config.Destination.Path != "" {
 		if _, err := os.Stat(config.Destination.Path); err != nil {
-			return fmt.Errorf("destination path '%s' does not exist", config.Destination.Path)
+			add("destination.path", withExitCode(exitNoDestination, fmt.Errorf("destination path '%s' does not exist", config.Destination.Path)))
+		} else if _, err := os.Stat(filepath.Join(config.Destination.Path, "EasyAntiCheat")); err != nil {
+			// destination.path には "EasyAntiCheat" ディレクトリが存在すること
+			add("destination.path", withExitCode(exitNoDestination, fmt.Errorf("EasyAntiCheat directory not found in destination path '%s'", config.Destination.Path)))
 		}
+	}
+
+	// source.paths の各フォルダが存在すること
+	problems = append(problems, checkSourcePaths(config.Source.Paths)...)
 
-		// destination.path には "EasyAntiCheat" ディレクトリが存在すること
-		eacPath := config.Destination.Path + "/EasyAntiCheat"
-		if _, err := os.Stat(eacPath); err != nil {
-			return fmt.Errorf("EasyAntiCheat directory not found in destination path '%s'", config.Destination.Path)
+	// source.include と source.exclude のパターンの書式が正しいこと
+	for _, list := range []struct {
+		key      string
+		patterns []string
+	}{{"source.include", config.Source.Include}, {"source.exclude", config.Source.Exclude}} {
+		for i, pattern := range list.patterns {
+			if err := (pathFilter{Include: []string{pattern}}).validate(); err != nil {
+				add(fmt.Sprintf("%s[%d]", list.key, i), err)
+			}
 		}
 	}
 
+	// source.orientation が正しい値であること
+	if err := validateOrientation(config.Source.Orientation); err != nil {
+		add("source.orientation", err)
+	}
+
+	// source.max_pixels・source.min_width・source.min_height が負の値でないこと
+	if config.Source.MaxPixels < 0 {
+		add("source.max_pixels", fmt.Errorf("source max_pixels must not be negative"))
+	}
+	if config.Source.MinWidth < 0 {
+		add("source.min_width", fmt.Errorf("source min_width must not be negative"))
+	}
+	if config.Source.MinHeight < 0 {
+		add("source.min_height", fmt.Errorf("source min_height must not be negative"))
+	}
+
+	// source.min_aspect と source.max_aspect が負の値でなく、範囲が正しいこと
+	if config.Source.MinAspect < 0 {
+		add("source.min_aspect", fmt.Errorf("source aspect ratio limits must not be negative"))
+	}
+	if config.Source.MaxAspect < 0 {
+		add("source.max_aspect", fmt.Errorf("source aspect ratio limits must not be negative"))
+	}
+	if config.Source.MaxAspect > 0 && config.Source.MinAspect > config.Source.MaxAspect {
+		add("source.min_aspect", fmt.Errorf("source min_aspect must be less than or equal to max_aspect"))
+	}
+
+	// selection.duplicate_threshold が 0 以上 64 以下であること
+	if config.Selection.DuplicateThreshold < 0 || config.Selection.DuplicateThreshold > 64 {
+		add("selection.duplicate_threshold", fmt.Errorf("selection duplicate_threshold must be between 0 and 64"))
+	}
+
+	// tags.include と tags.exclude の条件式の書式が正しいこと
+	for _, list := range []struct {
+		key   string
+		exprs []string
+	}{{"tags.include", config.Tags.Include}, {"tags.exclude", config.Tags.Exclude}} {
+		for i, expr := range list.exprs {
+			if _, err := parseTagExpr(expr); err != nil {
+				add(fmt.Sprintf("%s[%d]", list.key, i), err)
+			}
+		}
+	}
+
+	// screenshot.max_age_days が負の値でないこと
+	if config.Screenshot.MaxAgeDays < 0 {
+		add("screenshot.max_age_days", fmt.Errorf("screenshot max_age_days must not be negative"))
+	}
+
 	// destination.width が 0 より大きいこと
 	if config.Destination.Width <= 0 {
-		return fmt.Errorf("destination width must be greater than 0")
+		add("destination.width", fmt.Errorf("destination width must be greater than 0"))
 	}
 
 	// destination.height が 0 より大きいこと
 	if config.Destination.Height <= 0 {
-		return fmt.Errorf("destination height must be greater than 0")
+		add("destination.height", fmt.Errorf("destination height must be greater than 0"))
+	}
+
+	// log.level と log.format が対応している値であること
+	if _, err := parseLogLevel(config.Log.Level); err != nil {
+		add("log.level", err)
+	}
+	if _, err := newLogHandler(io.Discard, config.Log.Format, slog.LevelInfo); err != nil {
+		add("log.format", err)
+	}
+
+	// log.max_age_days と log.max_size_mb が負の値でないこと
+	if config.Log.MaxAgeDays < 0 {
+		add("log.max_age_days", fmt.Errorf("log max_age_days must not be negative"))
+	}
+	if config.Log.MaxSizeMB < 0 {
+		add("log.max_size_mb", fmt.Errorf("log max_size_mb must not be negative"))
 	}
 
-	return nil
+	return problems
 }
@@ -5,6 +5,8 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+
+	"gopkg.in/yaml.v3"
 )
 
 func TestLoadConfig(t *testing.T) {
@@ -223,3 +225,207 @@ destination:
 		t.Errorf("Expected destination height to be 720, got %d", config.Destination.Height)
 	}
 }
+
+func TestCheckConfigSourceFilters(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(config *Config)
+		wantErr bool
+	}{
+		{"Defaults", func(config *Config) {}, false},
+		{"Landscape", func(config *Config) { config.Source.Orientation = "landscape" }, false},
+		{"Invalid orientation", func(config *Config) { config.Source.Orientation = "square" }, true},
+		{"Aspect range", func(config *Config) { config.Source.MinAspect, config.Source.MaxAspect = 1.2, 2.0 }, false},
+		{"Inverted aspect range", func(config *Config) { config.Source.MinAspect, config.Source.MaxAspect = 2.0, 1.2 }, true},
+		{"Negative aspect", func(config *Config) { config.Source.MinAspect = -1 }, true},
+		{"Negative max aspect", func(config *Config) { config.Source.MaxAspect = -1 }, true},
+		{"Minimum size", func(config *Config) { config.Source.MinWidth, config.Source.MinHeight = 1920, 1080 }, false},
+		{"Negative min width", func(config *Config) { config.Source.MinWidth = -1 }, true},
+		{"Negative min height", func(config *Config) { config.Source.MinHeight = -1 }, true},
+		{"No pixel limit", func(config *Config) { config.Source.MaxPixels = 0 }, false},
+		{"Negative max pixels", func(config *Config) { config.Source.MaxPixels = -1 }, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var config Config
+			setDefaults(&config, nil)
+			tt.modify(&config)
+			err := checkConfig(&config)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("checkConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestOverrideConfigWithEnvFloat(t *testing.T) {
+	os.Setenv("SOURCE_MIN_ASPECT", "1.5")
+	defer os.Unsetenv("SOURCE_MIN_ASPECT")
+
+	var config Config
+	overrideConfigWithEnv(&config)
+	if config.Source.MinAspect != 1.5 {
+		t.Errorf("Expected source min aspect to be 1.5, got %v", config.Source.MinAspect)
+	}
+}
+
+func TestLoadConfigWithSourcePaths(t *testing.T) {
+	tmpDir := t.TempDir()
+	sourceA := filepath.Join(tmpDir, "a")
+	sourceB := filepath.Join(tmpDir, "b")
+	destinationDirPath := filepath.Join(tmpDir, "destination")
+	os.MkdirAll(sourceA, os.ModePerm)
+	os.MkdirAll(sourceB, os.ModePerm)
+	os.MkdirAll(filepath.Join(destinationDirPath, "EasyAntiCheat"), os.ModePerm)
+
+	configContent := `
+source:
+	paths:
+		- path: {{ .A }}
+		- path: {{ .B }}
+			recursive: false
+			exclude:
+				- edits
+			weight: 2.5
+destination:
+	path: {{ .Destination.Path }}
+`
+	configContent = strings.TrimSpace(configContent)
+	configContent = strings.ReplaceAll(configContent, "\t", "  ")
+	configContent = strings.ReplaceAll(configContent, "{{ .A }}", sourceA)
+	configContent = strings.ReplaceAll(configContent, "{{ .B }}", sourceB)
+	configContent = strings.ReplaceAll(configContent, "{{ .Destination.Path }}", destinationDirPath)
+
+	configPath := filepath.Join(tmpDir, "config.yml")
+	if err := os.WriteFile(configPath, []byte(configContent), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	config, err := LoadConfig(configPath)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if len(config.Source.Paths) != 2 {
+		t.Fatalf("Expected 2 source paths, got %d", len(config.Source.Paths))
+	}
+	second := config.Source.Paths[1]
+	if second.Path != sourceB || second.Recursive == nil || *second.Recursive || second.Weight == nil || *second.Weight != 2.5 || len(second.Exclude) != 1 {
+		t.Errorf("Unexpected second source path: %+v", second)
+	}
+	if config.Source.Paths[0].Recursive != nil {
+		t.Errorf("Expected first source recursive to be unset")
+	}
+}
+
+// writeExplicitConfig writes a config file with valid source and destination paths.
+// sourceExtra is appended to the source section and extra to the end of the file.
+func writeExplicitConfig(t *testing.T, sourceExtra, extra string) string {
+	t.Helper()
+	source := t.TempDir()
+	destination := t.TempDir()
+	if err := os.Mkdir(filepath.Join(destination, "EasyAntiCheat"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	content := "source:\n  path: " + source + "\n" + sourceExtra + "destination:\n  path: " + destination + "\n" + extra
+	path := filepath.Join(t.TempDir(), "config.yml")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadConfigExplicitZeroValues(t *testing.T) {
+	tests := []struct {
+		name        string
+		sourceExtra string
+		extra       string
+		env         map[string]string
+		check       func(config *Config) bool
+	}{
+		// bool: source.recursive defaults to true
+		{"bool from YAML", "  recursive: false\n", "", nil, func(c *Config) bool { return !c.Source.Recursive }},
+		{"bool from env", "", "", map[string]string{"SOURCE_RECURSIVE": "false"}, func(c *Config) bool { return !c.Source.Recursive }},
+		{"bool env overrides YAML", "  recursive: false\n", "", map[string]string{"SOURCE_RECURSIVE": "true"}, func(c *Config) bool { return c.Source.Recursive }},
+		{"bool unset", "", "", nil, func(c *Config) bool { return c.Source.Recursive }},
+		{"bool null", "  recursive:\n", "", nil, func(c *Config) bool { return c.Source.Recursive }},
+		// int: log.max_age_days defaults to 30 and selection.duplicate_threshold to 10
+		{"int from YAML", "", "log:\n  max_age_days: 0\n", nil, func(c *Config) bool { return c.Log.MaxAgeDays == 0 }},
+		{"int from env", "", "", map[string]string{"SELECTION_DUPLICATE_THRESHOLD": "0"}, func(c *Config) bool { return c.Selection.DuplicateThreshold == 0 }},
+		{"int unset", "", "", nil, func(c *Config) bool { return c.Log.MaxAgeDays == 30 && c.Selection.DuplicateThreshold == 10 }},
+		// string: source.orientation defaults to any and log.format to text
+		{"string from YAML", "  orientation: \"\"\n", "log:\n  format: ''\n", nil, func(c *Config) bool { return c.Source.Orientation == "" && c.Log.Format == "" }},
+		{"string unset", "", "", nil, func(c *Config) bool { return c.Source.Orientation == "any" && c.Log.Format == "text" }},
+		// float64 is covered by TestExplicitZeroFloat, as no float option of Config has a non-zero default
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+			config, err := LoadConfig(writeExplicitConfig(t, tt.sourceExtra, tt.extra))
+			if err != nil {
+				t.Fatalf("LoadConfig() error = %v", err)
+			}
+			if !tt.check(config) {
+				t.Errorf("Unexpected config: %+v", *config)
+			}
+		})
+	}
+}
+
+func TestExplicitZeroFloat(t *testing.T) {
+	// overlay.text.size of testNestedConfig defaults to 1.5
+	tests := []struct {
+		name string
+		yaml string
+		env  string
+		want float64
+	}{
+		{"from YAML", "overlay:\n  text:\n    size: 0\n", "", 0},
+		{"from env", "", "0", 0},
+		{"unset", "overlay:\n  enabled: true\n", "", 1.5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("OVERLAY_TEXT_SIZE", tt.env)
+			var root yaml.Node
+			if err := yaml.Unmarshal([]byte(tt.yaml), &root); err != nil {
+				t.Fatal(err)
+			}
+			var config testNestedConfig
+			explicit := explicitFields{}
+			if root.Kind != 0 {
+				if err := root.Decode(&config); err != nil {
+					t.Fatal(err)
+				}
+				explicit = yamlExplicitFields(&root)
+			}
+			envFields, problems := overrideConfigWithEnv(&config)
+			if len(problems) != 0 {
+				t.Fatalf("Unexpected problems: %+v", problems)
+			}
+			for key := range envFields {
+				explicit[key] = true
+			}
+			setDefaults(&config, explicit)
+			if config.Overlay.Text.Size != tt.want {
+				t.Errorf("Expected size %v, got %v", tt.want, config.Overlay.Text.Size)
+			}
+		})
+	}
+}
+
+func TestYAMLExplicitFields(t *testing.T) {
+	var root yaml.Node
+	data := "source:\n  recursive: false\n  path:\ndestination:\n  width: 0\nlog: ~\n"
+	if err := yaml.Unmarshal([]byte(data), &root); err != nil {
+		t.Fatal(err)
+	}
+	fields := yamlExplicitFields(&root)
+	if len(fields) != 2 || !fields["source.recursive"] || !fields["destination.width"] {
+		t.Errorf("Unexpected explicit fields: %v", fields)
+	}
+}
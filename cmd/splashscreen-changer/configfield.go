@@ -0,0 +1,149 @@
+package main
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// configField は、設定値の構造体の末端の設定項目です。
+type configField struct {
+	// Key は、yaml タグの名前をピリオドで連結したキー（例: source.max_pixels、destination.copy_metadata）
+	Key string
+	// EnvKey は、対応する環境変数のキー（例: SOURCE_MAX_PIXELS、OVERLAY_TEXT_COLOR）。env:"-" が付いている場合は空
+	EnvKey string
+	Field  reflect.StructField
+	Value  reflect.Value
+}
+
+var (
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	durationType        = reflect.TypeOf(time.Duration(0))
+)
+
+// isConfigSection は、型が入れ子のセクションとしてたどる構造体かを返します。
+// encoding.TextUnmarshaler を実装する構造体（time.Time など）は、1 つの設定項目として扱います。
+func isConfigSection(t reflect.Type) bool {
+	return t.Kind() == reflect.Struct && !reflect.PointerTo(t).Implements(textUnmarshalerType)
+}
+
+// walkConfigFields は、設定値の構造体を再帰的にたどり、末端の設定項目ごとに fn を呼び出します。
+// config は構造体へのポインタです。セクションに env:"-" が付いている場合は、その中の項目の EnvKey も空になります。
+func walkConfigFields(config any, fn func(field configField)) {
+	var walk func(v reflect.Value, key, envKey string)
+	walk = func(v reflect.Value, key, envKey string) {
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			name := getYAMLName(field)
+			if name == "-" {
+				continue
+			}
+			fieldKey, fieldEnvKey := name, strings.ToUpper(name)
+			if key != "" {
+				fieldKey = key + "." + name
+				fieldEnvKey = envKey + "_" + fieldEnvKey
+			}
+			if field.Tag.Get("env") == "-" || (key != "" && envKey == "") {
+				fieldEnvKey = ""
+			}
+
+			if isConfigSection(field.Type) {
+				walk(v.Field(i), fieldKey, fieldEnvKey)
+				continue
+			}
+			fn(configField{Key: fieldKey, EnvKey: fieldEnvKey, Field: field, Value: v.Field(i)})
+		}
+	}
+	walk(reflect.ValueOf(config).Elem(), "", "")
+}
+
+// setFieldFromString は、環境変数や default タグの文字列を解析し、設定項目に設定します。
+// リストはカンマ区切り（例: a,b）、マップは key=value のカンマ区切り（例: a=1,b=2）で指定します。
+func setFieldFromString(v reflect.Value, value string) error {
+	if v.CanAddr() {
+		if u, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok {
+			if err := u.UnmarshalText([]byte(value)); err != nil {
+				return fmt.Errorf("invalid %s value '%s': %w", v.Type(), value, err)
+			}
+			return nil
+		}
+	}
+
+	switch {
+	case v.Type() == durationType:
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("invalid duration value '%s'", value)
+		}
+		v.SetInt(int64(d))
+		return nil
+	case v.Kind() == reflect.String:
+		v.SetString(value)
+		return nil
+	case v.Kind() == reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid bool value '%s'", value)
+		}
+		v.SetBool(b)
+		return nil
+	case v.CanInt():
+		n, err := strconv.ParseInt(value, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid int value '%s'", value)
+		}
+		v.SetInt(n)
+		return nil
+	case v.CanUint():
+		n, err := strconv.ParseUint(value, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid uint value '%s'", value)
+		}
+		v.SetUint(n)
+		return nil
+	case v.CanFloat():
+		f, err := strconv.ParseFloat(value, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid float value '%s'", value)
+		}
+		v.SetFloat(f)
+		return nil
+	case v.Kind() == reflect.Slice:
+		items := splitList(value)
+		list := reflect.MakeSlice(v.Type(), len(items), len(items))
+		for i, item := range items {
+			if err := setFieldFromString(list.Index(i), item); err != nil {
+				return err
+			}
+		}
+		v.Set(list)
+		return nil
+	case v.Kind() == reflect.Map:
+		m := reflect.MakeMap(v.Type())
+		for _, item := range splitList(value) {
+			k, val, ok := strings.Cut(item, "=")
+			if !ok {
+				return fmt.Errorf("invalid map entry '%s' (expected key=value)", item)
+			}
+			mapKey := reflect.New(v.Type().Key()).Elem()
+			if err := setFieldFromString(mapKey, strings.TrimSpace(k)); err != nil {
+				return err
+			}
+			mapValue := reflect.New(v.Type().Elem()).Elem()
+			if err := setFieldFromString(mapValue, strings.TrimSpace(val)); err != nil {
+				return err
+			}
+			m.SetMapIndex(mapKey, mapValue)
+		}
+		v.Set(m)
+		return nil
+	}
+	return fmt.Errorf("%s values cannot be set from a string", v.Type())
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testColor is a custom config type parsed from a string such as "#ff8000".
+type testColor struct {
+	R, G, B uint8
+}
+
+func (c *testColor) UnmarshalText(text []byte) error {
+	_, err := fmt.Sscanf(string(text), "#%02x%02x%02x", &c.R, &c.G, &c.B)
+	return err
+}
+
+// testNestedConfig has nested sections and the field types supported by the config engine.
+type testNestedConfig struct {
+	Overlay struct {
+		Enabled bool `yaml:"enabled" default:"true"`
+		Text    struct {
+			Color   testColor     `yaml:"color" default:"#ffffff"`
+			Size    float64       `yaml:"size" default:"1.5"`
+			Fade    time.Duration `yaml:"fade" default:"500ms"`
+			Content string        `yaml:"content" required:"true"`
+		} `yaml:"text"`
+	} `yaml:"overlay"`
+	Limits struct {
+		Sizes   []int          `yaml:"sizes" default:"640,800"`
+		Weights map[string]int `yaml:"weights"`
+	} `yaml:"limits"`
+	Internal struct {
+		Token string `yaml:"token"`
+	} `yaml:"internal" env:"-"`
+}
+
+func TestWalkConfigFields(t *testing.T) {
+	var config testNestedConfig
+	var keys, envKeys []string
+	walkConfigFields(&config, func(field configField) {
+		keys = append(keys, field.Key)
+		envKeys = append(envKeys, field.EnvKey)
+	})
+
+	wantKeys := []string{"overlay.enabled", "overlay.text.color", "overlay.text.size", "overlay.text.fade", "overlay.text.content", "limits.sizes", "limits.weights", "internal.token"}
+	wantEnvKeys := []string{"OVERLAY_ENABLED", "OVERLAY_TEXT_COLOR", "OVERLAY_TEXT_SIZE", "OVERLAY_TEXT_FADE", "OVERLAY_TEXT_CONTENT", "LIMITS_SIZES", "LIMITS_WEIGHTS", ""}
+	if !reflect.DeepEqual(keys, wantKeys) {
+		t.Errorf("Expected keys %v, got %v", wantKeys, keys)
+	}
+	if !reflect.DeepEqual(envKeys, wantEnvKeys) {
+		t.Errorf("Expected env keys %v, got %v", wantEnvKeys, envKeys)
+	}
+}
+
+func TestSetDefaultsNested(t *testing.T) {
+	var config testNestedConfig
+	setDefaults(&config, explicitFields{"overlay.enabled": true})
+
+	if config.Overlay.Enabled {
+		t.Error("Expected the explicit overlay.enabled to be kept")
+	}
+	if config.Overlay.Text.Color != (testColor{255, 255, 255}) {
+		t.Errorf("Unexpected color: %+v", config.Overlay.Text.Color)
+	}
+	if config.Overlay.Text.Size != 1.5 || config.Overlay.Text.Fade != 500*time.Millisecond {
+		t.Errorf("Unexpected text settings: %+v", config.Overlay.Text)
+	}
+	if !reflect.DeepEqual(config.Limits.Sizes, []int{640, 800}) {
+		t.Errorf("Unexpected sizes: %v", config.Limits.Sizes)
+	}
+
+	problems := requiredProblems(&config)
+	if len(problems) != 1 || problems[0].Field != "overlay.text.content" {
+		t.Errorf("Expected overlay.text.content to be required, got %+v", problems)
+	}
+}
+
+func TestOverrideConfigWithEnvNested(t *testing.T) {
+	t.Setenv("OVERLAY_TEXT_COLOR", "#ff8000")
+	t.Setenv("OVERLAY_TEXT_FADE", "1m30s")
+	t.Setenv("LIMITS_SIZES", "1280, 1920")
+	t.Setenv("LIMITS_WEIGHTS", "sunset=3,night=1")
+	t.Setenv("OVERLAY_TEXT_SIZE", "large")
+	t.Setenv("INTERNAL_TOKEN", "secret")
+
+	var config testNestedConfig
+	explicit, problems := overrideConfigWithEnv(&config)
+
+	if config.Overlay.Text.Color != (testColor{0xff, 0x80, 0x00}) {
+		t.Errorf("Unexpected color: %+v", config.Overlay.Text.Color)
+	}
+	if config.Overlay.Text.Fade != 90*time.Second {
+		t.Errorf("Unexpected fade: %v", config.Overlay.Text.Fade)
+	}
+	if !reflect.DeepEqual(config.Limits.Sizes, []int{1280, 1920}) {
+		t.Errorf("Unexpected sizes: %v", config.Limits.Sizes)
+	}
+	if !reflect.DeepEqual(config.Limits.Weights, map[string]int{"sunset": 3, "night": 1}) {
+		t.Errorf("Unexpected weights: %v", config.Limits.Weights)
+	}
+	if config.Internal.Token != "" {
+		t.Error("Expected env:\"-\" sections to be ignored")
+	}
+	if !explicit["overlay.text.fade"] || explicit["overlay.text.size"] {
+		t.Errorf("Unexpected explicit fields: %v", explicit)
+	}
+	if len(problems) != 1 || problems[0].Env != "OVERLAY_TEXT_SIZE" || !strings.Contains(problems[0].Message, "invalid float value") {
+		t.Errorf("Expected a problem for OVERLAY_TEXT_SIZE, got %+v", problems)
+	}
+}
+
+func TestSetFieldFromStringErrors(t *testing.T) {
+	var config testNestedConfig
+	tests := []struct {
+		value reflect.Value
+		input string
+	}{
+		{reflect.ValueOf(&config.Overlay.Text.Fade).Elem(), "soon"},
+		{reflect.ValueOf(&config.Overlay.Text.Color).Elem(), "orange"},
+		{reflect.ValueOf(&config.Limits.Sizes).Elem(), "640,wide"},
+		{reflect.ValueOf(&config.Limits.Weights).Elem(), "sunset"},
+	}
+	for _, tt := range tests {
+		if err := setFieldFromString(tt.value, tt.input); err == nil {
+			t.Errorf("Expected an error for %q into %s", tt.input, tt.value.Type())
+		}
+	}
+}
+
+func TestConfigDefaultTagsAreValid(t *testing.T) {
+	// setDefaults ignores parse errors, so every default tag of Config is checked here
+	walkConfigFields(&Config{}, func(field configField) {
+		if defaultValue, ok := field.Field.Tag.Lookup("default"); ok {
+			if err := setFieldFromString(field.Value, defaultValue); err != nil {
+				t.Errorf("Invalid default of %s: %v", field.Key, err)
+			}
+		}
+	})
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+)
+
+// configFlag は、設定項目を上書きするコマンドライン引数（例: --destination.width=1280）です。
+// 指定された値は values に保存し、設定ファイルと環境変数を読み込んだ後に overrideConfigWithFlags で設定します。
+// 同じ引数を複数回指定した場合は、最後の値を使用します。
+type configFlag struct {
+	key    string
+	typ    reflect.Type
+	values map[string][]string
+}
+
+// sourcePathsType は、source.paths の型
+var sourcePathsType = reflect.TypeOf([]SourcePath(nil))
+
+// addConfigFlags は、Config 構造体の各設定項目のコマンドライン引数を追加し、指定された値を保存するマップを返します。
+// 引数の名前は設定項目のキー（例: destination.width）です。環境変数で指定できない項目（env:"-"）は、source.paths を除いて追加しません。
+func addConfigFlags(fs *flag.FlagSet) map[string][]string {
+	values := map[string][]string{}
+	walkConfigFields(&Config{}, func(field configField) {
+		if fs.Lookup(field.Key) != nil {
+			return
+		}
+		switch {
+		case field.Field.Type == sourcePathsType:
+			fs.Var(&sourcePathsFlag{key: field.Key, values: values}, field.Key, sourcePathsFlagHelp)
+		case field.EnvKey != "":
+			fs.Var(&configFlag{key: field.Key, typ: field.Field.Type, values: values}, field.Key, configFieldHelp(field))
+		}
+	})
+	return values
+}
+
+func (f *configFlag) String() string {
+	// flag パッケージは、デフォルト値の判別のためにゼロ値の configFlag でも呼び出す
+	if f.values == nil || len(f.values[f.key]) == 0 {
+		return ""
+	}
+	return f.values[f.key][len(f.values[f.key])-1]
+}
+
+// Set は、値を設定項目の型として解析できるかをチェックし、保存します。
+func (f *configFlag) Set(value string) error {
+	if err := setFieldFromString(reflect.New(f.typ).Elem(), value); err != nil {
+		return err
+	}
+	f.values[f.key] = []string{value}
+	return nil
+}
+
+// IsBoolFlag は、真偽値の設定項目の場合に、値を省略できるようにします（例: --source.recursive）。
+func (f *configFlag) IsBoolFlag() bool {
+	return f.typ != nil && f.typ.Kind() == reflect.Bool
+}
+
+// sourcePathsFlag は、source.paths のソースフォルダを 1 つ指定するコマンドライン引数（例: --source.paths=C:\Pictures）です。
+// 複数回指定でき、指定した順に source.paths の項目になります。各項目の path 以外の設定は、source の設定を使用します。
+type sourcePathsFlag struct {
+	key    string
+	values map[string][]string
+}
+
+// sourcePathsFlagHelp は、sourcePathsFlag の説明
+const sourcePathsFlagHelp = "Source directory to pick images from. Can be specified multiple times, and replaces source.paths of the configuration file"
+
+func (f *sourcePathsFlag) String() string {
+	if f.values == nil {
+		return ""
+	}
+	return strings.Join(f.values[f.key], ", ")
+}
+
+// Set は、ソースフォルダのパスを追加します。
+func (f *sourcePathsFlag) Set(value string) error {
+	if value == "" {
+		return errors.New("path must not be empty")
+	}
+	f.values[f.key] = append(f.values[f.key], value)
+	return nil
+}
+
+// isConfigFlag は、コマンドライン引数が設定項目を上書きする引数かを返します。
+func isConfigFlag(f *flag.Flag) bool {
+	switch f.Value.(type) {
+	case *configFlag, *sourcePathsFlag:
+		return true
+	}
+	return false
+}
+
+// overrideConfigWithFlags は、コマンドライン引数で指定された値で設定を上書きし、上書きした項目を返します。
+// 環境変数より後に呼び出し、コマンドライン引数を優先します。
+func overrideConfigWithFlags(config any, values map[string][]string) (explicitFields, []configProblem) {
+	explicit := explicitFields{}
+	var problems []configProblem
+
+	walkConfigFields(config, func(field configField) {
+		value, ok := values[field.Key]
+		if !ok || len(value) == 0 {
+			return
+		}
+		// source.paths は、指定されたパスごとに項目を作り、設定ファイルの値を置き換える
+		if field.Field.Type == sourcePathsType {
+			paths := make([]SourcePath, len(value))
+			for i, path := range value {
+				paths[i] = SourcePath{Path: path}
+			}
+			field.Value.Set(reflect.ValueOf(paths))
+			explicit[field.Key] = true
+			return
+		}
+		if err := setFieldFromString(field.Value, value[len(value)-1]); err != nil {
+			problems = append(problems, configProblem{Field: field.Key, Flag: "--" + field.Key, Message: err.Error(), err: err})
+			return
+		}
+		explicit[field.Key] = true
+	})
+	return explicit, problems
+}
+
+// configFieldHelp は、設定項目の説明を、デフォルト値がある場合はそれを付けて返します。
+func configFieldHelp(field configField) string {
+	help := field.Field.Tag.Get("help")
+	if defaultValue := field.Field.Tag.Get("default"); defaultValue != "" {
+		help += fmt.Sprintf(" (default: %s)", defaultValue)
+	}
+	return help
+}
+
+// printConfigFlagHelp は、設定項目を上書きするコマンドライン引数の一覧を表示します。
+func printConfigFlagHelp(w io.Writer) {
+	fmt.Fprintln(w, "Configuration Options (override the configuration file and environment variables):")
+	walkConfigFields(&Config{}, func(field configField) {
+		switch {
+		case field.Field.Type == sourcePathsType:
+			fmt.Fprintf(w, "  %-38s %s\n", "--"+field.Key+"=path", sourcePathsFlagHelp)
+			return
+		case field.EnvKey == "":
+			return
+		}
+		name := "--" + field.Key
+		if field.Field.Type.Kind() != reflect.Bool {
+			name += "=value"
+		}
+		fmt.Fprintf(w, "  %-38s %s\n", name, configFieldHelp(field))
+	})
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestConfigFlagPrecedence(t *testing.T) {
+	path := writeExplicitConfig(t, "", "  width: 1280\n  height: 720\n")
+	t.Setenv("DESTINATION_WIDTH", "1600")
+	t.Setenv("DESTINATION_HEIGHT", "900")
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	values := addConfigFlags(fs)
+	if _, err := parseFlags(fs, []string{"--destination.width=1920", "--source.skip_hidden"}); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := loadConfig(path, false, values)
+	if err != nil {
+		t.Fatalf("loadConfig failed: %v", err)
+	}
+	config := loaded.Config
+	// flag > env > file > default
+	if config.Destination.Width != 1920 || config.Destination.Height != 900 || !config.Source.SkipHidden || config.Log.Level != "info" {
+		t.Errorf("Unexpected values: %+v, %+v", config.Destination, config.Log)
+	}
+	if origin := loaded.Origins["destination.width"]; origin != "--destination.width (command-line flag)" {
+		t.Errorf("Unexpected origin of destination.width: %q", origin)
+	}
+}
+
+func TestConfigFlagProblems(t *testing.T) {
+	// Values that cannot be parsed are rejected while parsing the flags
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	addConfigFlags(fs)
+	if _, err := parseFlags(fs, []string{"--destination.width=wide"}); err == nil {
+		t.Error("Expected an error for an invalid integer")
+	}
+	if _, err := parseFlags(fs, []string{"--source.paths="}); err == nil {
+		t.Error("Expected an error for an empty source path")
+	}
+
+	// Invalid values are attributed to the flag
+	fs = flag.NewFlagSet("test", flag.ContinueOnError)
+	values := addConfigFlags(fs)
+	if _, err := parseFlags(fs, []string{"--destination.width", "-1"}); err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "EasyAntiCheat"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("SOURCE_PATH", dir)
+	t.Setenv("DESTINATION_PATH", dir)
+	_, err := loadConfig(filepath.Join(dir, "missing.yml"), false, values)
+	var configErr *configError
+	if !errors.As(err, &configErr) || len(configErr.Problems) != 1 || configErr.Problems[0].Flag != "--destination.width" {
+		t.Errorf("Expected a problem for --destination.width, got %v", err)
+	}
+}
+
+func TestConfigFlagSourcePaths(t *testing.T) {
+	dir := t.TempDir()
+	first, second := filepath.Join(dir, "first"), filepath.Join(dir, "second")
+	path := writeExplicitConfig(t, "  paths:\n    - path: "+filepath.ToSlash(dir)+"\n      weight: 2\n", "")
+
+	// Each flag adds one source path, and the flags replace the paths of the configuration file
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	values := addConfigFlags(fs)
+	if _, err := parseFlags(fs, []string{"--source.paths", first, "--source.paths=" + second}); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := loadConfig(path, false, values)
+	var configErr *configError
+	if !errors.As(err, &configErr) {
+		t.Fatalf("Expected problems for the missing source paths, got %v", err)
+	}
+	// Problems of the paths are attributed to the flag
+	for _, problem := range configErr.Problems {
+		if strings.HasPrefix(problem.Field, "source.paths") && problem.Flag != "--source.paths" {
+			t.Errorf("Expected the problem to be attributed to --source.paths, got %+v", problem)
+		}
+	}
+
+	for _, name := range []string{first, second} {
+		if err := os.Mkdir(name, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	loaded, err := loadConfig(path, false, values)
+	if err != nil {
+		t.Fatalf("loadConfig failed: %v", err)
+	}
+	paths := loaded.Config.Source.Paths
+	if len(paths) != 2 || paths[0].Path != first || paths[1].Path != second || paths[0].Weight != nil {
+		t.Errorf("Unexpected source paths: %+v", paths)
+	}
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// configFormat は、設定ファイルの形式です。
+type configFormat string
+
+const (
+	formatYAML configFormat = "YAML"
+	formatJSON configFormat = "JSON"
+	formatTOML configFormat = "TOML"
+)
+
+// TOML のテーブル（例: [source]、[[source.paths]]）またはキーと値（例: width = 800）の行
+var tomlLinePattern = regexp.MustCompile(`^(\[\[?[A-Za-z0-9_.\-" ]+\]\]?|[A-Za-z0-9_\-]+\s*=)`)
+
+// detectConfigFormat は、設定ファイルの形式を拡張子から判別します。
+// 拡張子が .yml・.yaml・.json・.toml 以外の場合は、コメントと空行を除いた最初の行から判別します。
+func detectConfigFormat(filename string, data []byte) configFormat {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".yml", ".yaml":
+		return formatYAML
+	case ".json":
+		return formatJSON
+	case ".toml":
+		return formatTOML
+	}
+
+	for _, line := range strings.Split(string(bytes.TrimPrefix(data, []byte("\uFEFF"))), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		switch {
+		case strings.HasPrefix(line, "{"):
+			return formatJSON
+		case tomlLinePattern.MatchString(line):
+			return formatTOML
+		}
+		return formatYAML
+	}
+	return formatYAML
+}
+
+// parseConfigNode は、設定ファイルの内容を形式に従って解析し、yaml.Node に変換します。
+// JSON は encoding/json の規則で解析し、各値の行・列の位置を付けて変換します。
+// TOML は値のみを変換するため、構文エラー以外の問題には位置がありません。
+// 空の設定ファイルの場合は、Kind が 0 のノードを返します。
+func parseConfigNode(format configFormat, data []byte) (*yaml.Node, []configProblem) {
+	var root yaml.Node
+	switch format {
+	case formatJSON:
+		return parseJSONNode(data)
+	case formatYAML:
+		if err := yaml.Unmarshal(data, &root); err != nil {
+			return nil, yamlErrorProblems(err, format)
+		}
+		return &root, nil
+	}
+
+	var values map[string]any
+	if _, err := toml.Decode(string(data), &values); err != nil {
+		return nil, []configProblem{tomlErrorProblem(err)}
+	}
+	if len(values) == 0 {
+		return &root, nil
+	}
+	var document yaml.Node
+	if err := document.Encode(values); err != nil {
+		return nil, []configProblem{{Message: "invalid TOML: " + err.Error(), err: err}}
+	}
+	root = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{&document}}
+	return &root, nil
+}
+
+// tomlErrorProblem は、TOML の構文エラーを、行・列の位置付きの問題に変換します。
+func tomlErrorProblem(err error) configProblem {
+	problem := configProblem{Message: "invalid TOML: " + err.Error(), err: err}
+	var parseErr toml.ParseError
+	if errors.As(err, &parseErr) {
+		problem.Message = "invalid TOML: " + parseErr.Message
+		problem.Line, problem.Column = parseErr.Position.Line, parseErr.Position.Col
+	}
+	return problem
+}
+
+// jsonNodeParser は、JSON のトークンを読み込み、行・列の位置付きの yaml.Node に変換します。
+type jsonNodeParser struct {
+	data []byte
+	dec  *json.Decoder
+}
+
+// parseJSONNode は、JSON を encoding/json で解析し、yaml.Node に変換します。
+// YAML として解析すると、JSON で使える \/ のようなエスケープが使えないため、YAML のパーサーは使用しません。
+// 同じキーが複数ある場合は、encoding/json と同じく最後の値を使用します。
+func parseJSONNode(data []byte) (*yaml.Node, []configProblem) {
+	data = bytes.TrimPrefix(data, []byte("\uFEFF"))
+	if len(bytes.TrimSpace(data)) == 0 {
+		return &yaml.Node{}, nil
+	}
+
+	p := &jsonNodeParser{data: data, dec: json.NewDecoder(bytes.NewReader(data))}
+	p.dec.UseNumber()
+	value, err := p.parseValue()
+	if err != nil {
+		return nil, []configProblem{p.errorProblem(err)}
+	}
+	// 値の後に余分な内容がないこと
+	if p.dec.More() {
+		problem := configProblem{Message: "invalid JSON: unexpected content after the top-level value", err: errors.New("unexpected content after the top-level value")}
+		problem.Line, problem.Column = p.position(p.tokenStart())
+		return nil, []configProblem{problem}
+	}
+	return &yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{value}, Line: value.Line, Column: value.Column}, nil
+}
+
+// parseValue は、次の値（オブジェクト・配列の場合はその中身も含む）を読み込みます。
+func (p *jsonNodeParser) parseValue() (*yaml.Node, error) {
+	line, column := p.position(p.tokenStart())
+	token, err := p.dec.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	node := &yaml.Node{Line: line, Column: column}
+	switch v := token.(type) {
+	case json.Delim:
+		if v == '[' {
+			node.Kind, node.Tag = yaml.SequenceNode, "!!seq"
+			for p.dec.More() {
+				item, err := p.parseValue()
+				if err != nil {
+					return nil, err
+				}
+				node.Content = append(node.Content, item)
+			}
+		} else {
+			node.Kind, node.Tag = yaml.MappingNode, "!!map"
+			if err := p.parseObject(node); err != nil {
+				return nil, err
+			}
+		}
+		// 閉じ括弧
+		if _, err := p.dec.Token(); err != nil {
+			return nil, err
+		}
+	case string:
+		node.Kind, node.Tag, node.Value, node.Style = yaml.ScalarNode, "!!str", v, yaml.DoubleQuotedStyle
+	case json.Number:
+		node.Kind, node.Tag, node.Value = yaml.ScalarNode, "!!int", v.String()
+		if strings.ContainsAny(v.String(), ".eE") {
+			node.Tag = "!!float"
+		}
+	case bool:
+		node.Kind, node.Tag, node.Value = yaml.ScalarNode, "!!bool", "false"
+		if v {
+			node.Value = "true"
+		}
+	case nil:
+		node.Kind, node.Tag, node.Value = yaml.ScalarNode, "!!null", "null"
+	}
+	return node, nil
+}
+
+// parseObject は、オブジェクトのキーと値を読み込み、node に追加します。
+func (p *jsonNodeParser) parseObject(node *yaml.Node) error {
+	index := map[string]int{}
+	for p.dec.More() {
+		key, err := p.parseValue()
+		if err != nil {
+			return err
+		}
+		value, err := p.parseValue()
+		if err != nil {
+			return err
+		}
+		if i, ok := index[key.Value]; ok {
+			node.Content[i], node.Content[i+1] = key, value
+			continue
+		}
+		index[key.Value] = len(node.Content)
+		node.Content = append(node.Content, key, value)
+	}
+	return nil
+}
+
+// tokenStart は、次のトークンの先頭のオフセットを返します。
+func (p *jsonNodeParser) tokenStart() int {
+	offset := int(p.dec.InputOffset())
+	for offset < len(p.data) && strings.IndexByte(" \t\r\n,:", p.data[offset]) >= 0 {
+		offset++
+	}
+	return offset
+}
+
+// position は、オフセットの行と列（いずれも 1 から数える）を返します。
+func (p *jsonNodeParser) position(offset int) (int, int) {
+	offset = min(offset, len(p.data))
+	before := p.data[:offset]
+	line := bytes.Count(before, []byte("\n")) + 1
+	lineStart := bytes.LastIndexByte(before, '\n') + 1
+	return line, utf8.RuneCount(before[lineStart:]) + 1
+}
+
+// errorProblem は、JSON の構文エラーを、行・列の位置付きの問題に変換します。
+func (p *jsonNodeParser) errorProblem(err error) configProblem {
+	offset := len(p.data)
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		offset = int(syntaxErr.Offset)
+	}
+	message := err.Error()
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		message = "unexpected end of JSON input"
+	}
+	problem := configProblem{Message: "invalid JSON: " + message, err: err}
+	problem.Line, problem.Column = p.position(offset)
+	return problem
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDetectConfigFormat(t *testing.T) {
+	tests := []struct {
+		filename string
+		content  string
+		want     configFormat
+	}{
+		{"config.yml", "{}", formatYAML},
+		{"config.YAML", "", formatYAML},
+		{"config.json", "source:\n", formatJSON},
+		{"config.toml", "", formatTOML},
+		{"config", "# comment\n\n{\"source\": {}}", formatJSON},
+		{"config", "# comment\n[source]\npath = 'C:\\\\'", formatTOML},
+		{"config", "[[source.paths]]\npath = 'a'", formatTOML},
+		{"config.conf", "log_level = \"debug\"", formatTOML},
+		{"config", "source:\n  path: a", formatYAML},
+		{"config", "", formatYAML},
+	}
+	for _, tt := range tests {
+		if got := detectConfigFormat(tt.filename, []byte(tt.content)); got != tt.want {
+			t.Errorf("detectConfigFormat(%q, %q) = %s, want %s", tt.filename, tt.content, got, tt.want)
+		}
+	}
+}
+
+// writeFormatConfig writes a config file with a source and destination directory substituted for {source} and {destination}.
+func writeFormatConfig(t *testing.T, name, content string) string {
+	t.Helper()
+	source := t.TempDir()
+	destination := t.TempDir()
+	if err := os.Mkdir(filepath.Join(destination, "EasyAntiCheat"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	// Backslashes in Windows paths must be escaped in JSON and TOML basic strings
+	content = strings.NewReplacer("{source}", filepath.ToSlash(source), "{destination}", filepath.ToSlash(destination)).Replace(content)
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadConfigJSON(t *testing.T) {
+	path := writeFormatConfig(t, "config.json", `{
+	"source": {
+		"path": "{source}",
+		"recursive": false,
+		"include": ["**/*.png"]
+	},
+	"destination": {
+		"path": "{destination}",
+		"width": 1280
+	}
+}
+`)
+	config, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if config.Source.Recursive || config.Destination.Width != 1280 || config.Destination.Height != 450 {
+		t.Errorf("Unexpected values: %+v, %+v", config.Source, config.Destination)
+	}
+	if len(config.Source.Include) != 1 || config.Source.Include[0] != "**/*.png" {
+		t.Errorf("Unexpected include: %v", config.Source.Include)
+	}
+
+	// Problems in JSON files have the same locations as in YAML files
+	path = writeFormatConfig(t, "config.json", "{\n  \"source\": {\"path\": \"{source}\"},\n  \"destination\": {\"path\": \"{destination}\", \"width\": 0}\n}\n")
+	problems := loadConfigProblems(t, path)
+	if len(problems) != 1 || problems[0].Field != "destination.width" || problems[0].Line != 3 {
+		t.Errorf("Expected destination.width on line 3, got %+v", problems)
+	}
+
+	path = writeFormatConfig(t, "config.json", "{\n  \"source\": {\n}\n")
+	problems = loadConfigProblems(t, path)
+	if len(problems) != 1 || !strings.HasPrefix(problems[0].Message, "invalid JSON") {
+		t.Errorf("Expected a JSON syntax error, got %+v", problems)
+	}
+
+	// Escapes valid only in JSON are accepted, and the last of duplicate keys wins as in encoding/json
+	path = writeFormatConfig(t, "config.json", `{
+	"source": {"path": "{source}", "include": ["shots\/**\/*.png"]},
+	"destination": {"path": "{destination}", "width": 640, "width": 1024}
+}
+`)
+	config, err = LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if len(config.Source.Include) != 1 || config.Source.Include[0] != "shots/**/*.png" || config.Destination.Width != 1024 {
+		t.Errorf("Unexpected values: %v, %+v", config.Source.Include, config.Destination)
+	}
+
+	for _, content := range []string{"{\"source\": {}} {}", "{\"source\": [1,]}"} {
+		path = writeFormatConfig(t, "config.json", content)
+		problems = loadConfigProblems(t, path)
+		if len(problems) != 1 || !strings.HasPrefix(problems[0].Message, "invalid JSON") || problems[0].Line != 1 {
+			t.Errorf("Expected a JSON syntax error on line 1 for %q, got %+v", content, problems)
+		}
+	}
+}
+
+func TestLoadConfigTOML(t *testing.T) {
+	path := writeFormatConfig(t, "config.toml", `# Configuration of splashscreen-changer
+[source]
+recursive = false
+min_aspect = 1.5
+exclude = ["thumbnails"]
+
+[[source.paths]]
+path = "{source}"
+weight = 3
+
+[destination]
+path = "{destination}"
+height = 720
+`)
+	loaded, err := loadConfig(path, false, nil)
+	if err != nil {
+		t.Fatalf("loadConfig failed: %v", err)
+	}
+	if len(loaded.Warnings) != 0 {
+		t.Errorf("Unexpected warnings: %+v", loaded.Warnings)
+	}
+	config := loaded.Config
+	if config.Source.Recursive || config.Source.MinAspect != 1.5 || config.Source.MaxPixels != 100000000 {
+		t.Errorf("Unexpected source values: %+v", config.Source)
+	}
+	if len(config.Source.Paths) != 1 || config.Source.Paths[0].Weight == nil || *config.Source.Paths[0].Weight != 3 {
+		t.Errorf("Unexpected source paths: %+v", config.Source.Paths)
+	}
+	if config.Destination.Width != 800 || config.Destination.Height != 720 {
+		t.Errorf("Unexpected size: %dx%d", config.Destination.Width, config.Destination.Height)
+	}
+}
+
+func TestLoadConfigTOMLProblems(t *testing.T) {
+	path := writeFormatConfig(t, "config.toml", "[source]\npath = \"{source}\"\nrecusive = true\n\n[destination]\npath = \"{destination}\"\nwidth = -1\n")
+	loaded, err := loadConfig(path, false, nil)
+	warnings := loaded.Warnings
+	if len(warnings) != 1 || warnings[0].Suggestion != "source.recursive" {
+		t.Errorf("Expected a warning for the unknown key, got %+v", warnings)
+	}
+	if err == nil || !strings.Contains(err.Error(), "destination width must be greater than 0") {
+		t.Errorf("Expected the width problem, got %v", err)
+	}
+
+	// Syntax errors are reported with their location
+	path = writeFormatConfig(t, "config", "[source]\npath = \"{source}\"\nrecursive = \n")
+	problems := loadConfigProblems(t, path)
+	if len(problems) != 1 || problems[0].Line != 3 || !strings.HasPrefix(problems[0].Message, "invalid TOML") {
+		t.Errorf("Expected a TOML syntax error on line 3, got %+v", problems)
+	}
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// includeKey は、ほかの設定ファイルを読み込む項目のキーです。インクルードしたファイルの上に、この設定ファイルの値を重ねます。
+const includeKey = "include"
+
+// configLayer は、マージする設定ファイルの 1 つです。
+type configLayer struct {
+	Path string
+	// Root は、設定ファイルの内容。空の設定ファイルの場合は Kind が 0
+	Root *yaml.Node
+}
+
+// localConfigPath は、設定ファイルに重ねるマシンごとの設定ファイルのパスを返します（例: config.yml → config.local.yml）。
+func localConfigPath(path string) string {
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + ".local" + ext
+}
+
+// loadConfigLayers は、設定ファイルと、その include で指定したファイル、マシンごとの設定ファイル（config.local.yml）を読み込み、マージする順に返します。
+// include で指定したファイルは、指定したファイルより前（優先度が低い）になります。設定ファイルが存在しない場合は、空のリストを返します。
+func loadConfigLayers(filename string) ([]configLayer, []configProblem, error) {
+	var layers []configLayer
+	var problems []configProblem
+	for _, path := range []string{filename, localConfigPath(filename)} {
+		if _, err := os.Stat(path); err != nil {
+			continue
+		}
+		fileLayers, fileProblems, err := readConfigLayers(path, nil)
+		if err != nil {
+			return nil, nil, err
+		}
+		layers = append(layers, fileLayers...)
+		problems = append(problems, fileProblems...)
+	}
+	return layers, problems, nil
+}
+
+// readConfigLayers は、設定ファイルと、その include で指定したファイルを再帰的に読み込みます。
+// stack は、インクルードしているファイルの絶対パスで、循環したインクルードの検出に使用します。
+func readConfigLayers(path string, stack []string) ([]configLayer, []configProblem, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	// 構文エラーの場合は、この設定ファイルの項目をチェックできない
+	root, problems := parseConfigNode(detectConfigFormat(path, data), data)
+	if len(problems) > 0 {
+		for i := range problems {
+			problems[i].File = path
+		}
+		return nil, problems, nil
+	}
+
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	stack = append(stack, absPath)
+
+	var layers []configLayer
+	for i, entry := range takeIncludes(root) {
+		problem := configProblem{Field: fmt.Sprintf("%s[%d]", includeKey, i), Line: entry.Line, Column: entry.Column, File: path}
+		includePath := entry.Value
+		if !filepath.IsAbs(includePath) {
+			includePath = filepath.Join(filepath.Dir(path), includePath)
+		}
+		absInclude, err := filepath.Abs(includePath)
+		if err != nil {
+			return nil, nil, err
+		}
+		if i := indexOf(stack, absInclude); i >= 0 {
+			problem.Message = fmt.Sprintf("include cycle: %s", strings.Join(append(stack[i:], absInclude), " -> "))
+			problems = append(problems, problem)
+			continue
+		}
+		if _, err := os.Stat(includePath); err != nil {
+			problem.Message = fmt.Sprintf("included file '%s' does not exist", includePath)
+			problems = append(problems, problem)
+			continue
+		}
+		includeLayers, includeProblems, err := readConfigLayers(includePath, stack)
+		if err != nil {
+			return nil, nil, err
+		}
+		layers = append(layers, includeLayers...)
+		problems = append(problems, includeProblems...)
+	}
+	return append(layers, configLayer{Path: path, Root: root}), problems, nil
+}
+
+// takeIncludes は、設定ファイルの include の項目を取り除き、指定されたパスのノードを返します。
+// include には、パスのリストか、1 つのパスを指定します。
+func takeIncludes(root *yaml.Node) []*yaml.Node {
+	if root.Kind != yaml.DocumentNode || len(root.Content) == 0 || root.Content[0].Kind != yaml.MappingNode {
+		return nil
+	}
+	mapping := root.Content[0]
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value != includeKey {
+			continue
+		}
+		value := mapping.Content[i+1]
+		mapping.Content = append(mapping.Content[:i], mapping.Content[i+2:]...)
+		switch value.Kind {
+		case yaml.SequenceNode:
+			return value.Content
+		case yaml.ScalarNode:
+			if value.Tag != "!!null" {
+				return []*yaml.Node{value}
+			}
+		}
+		return nil
+	}
+	return nil
+}
+
+// indexOf は、リストの中の値の位置を返します。ない場合は -1 を返します。
+func indexOf(list []string, value string) int {
+	for i, item := range list {
+		if item == value {
+			return i
+		}
+	}
+	return -1
+}
+
+// mergeConfigLayers は、設定ファイルを順に重ね、1 つの設定ファイルの内容にします。
+// マッピングはキーごとに再帰的にマージし、リストやスカラーは後の設定ファイルの値で置き換えます。値が空（null）の項目は無視します。
+// 項目のキーと、その値を指定した設定ファイルのパスの対応を合わせて返します。
+func mergeConfigLayers(layers []configLayer) (*yaml.Node, map[string]string) {
+	merged := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	origins := map[string]string{}
+	for _, layer := range layers {
+		if layer.Root.Kind != yaml.DocumentNode || len(layer.Root.Content) == 0 || layer.Root.Content[0].Kind != yaml.MappingNode {
+			continue
+		}
+		mergeMapping(merged, layer.Root.Content[0], "", layer.Path, origins)
+	}
+	if len(merged.Content) == 0 {
+		return &yaml.Node{}, origins
+	}
+	return &yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{merged}}, origins
+}
+
+// mergeMapping は、マッピング src を dst に重ねます。prefix は dst のキー、file は src の設定ファイルのパスです。
+func mergeMapping(dst, src *yaml.Node, prefix, file string, origins map[string]string) {
+	for i := 0; i+1 < len(src.Content); i += 2 {
+		keyNode, value := src.Content[i], src.Content[i+1]
+		if value.Tag == "!!null" {
+			continue
+		}
+		key := keyNode.Value
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+
+		index := -1
+		for j := 0; j+1 < len(dst.Content); j += 2 {
+			if dst.Content[j].Value == keyNode.Value {
+				index = j
+				break
+			}
+		}
+		if index >= 0 && dst.Content[index+1].Kind == yaml.MappingNode && value.Kind == yaml.MappingNode {
+			mergeMapping(dst.Content[index+1], value, key, file, origins)
+			continue
+		}
+
+		// 置き換える前の値の項目の対応を取り除く
+		for origin := range origins {
+			if strings.HasPrefix(origin, key+".") || strings.HasPrefix(origin, key+"[") {
+				delete(origins, origin)
+			}
+		}
+		if index >= 0 {
+			dst.Content[index], dst.Content[index+1] = keyNode, value
+		} else {
+			dst.Content = append(dst.Content, keyNode, value)
+		}
+		origins[key] = file
+		walkYAMLKeys(value, key, func(child string, _ *yaml.Node) {
+			origins[child] = file
+		})
+	}
+}
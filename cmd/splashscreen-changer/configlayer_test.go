@@ -0,0 +1,162 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeLayerFiles writes config files into a temporary directory and returns the directory.
+func writeLayerFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestLoadConfigLayers(t *testing.T) {
+	t.Setenv("DESTINATION_HEIGHT", "")
+	dir := writeLayerFiles(t, map[string]string{
+		"config.yml":       "include:\n  - common/base.yml\n  - extra.yml\ndestination:\n  width: 1280\n",
+		"common/base.yml":  "source:\n  recursive: false\n  exclude: [a, b]\ndestination:\n  width: 640\n  height: 300\n",
+		"extra.yml":        "source:\n  exclude: [c]\n",
+		"config.local.yml": "destination:\n  height: 500\n",
+	})
+	path := filepath.Join(dir, "config.yml")
+
+	loaded, err := loadConfig(path, true, nil)
+	var configErr *configError
+	if err != nil && !errors.As(err, &configErr) {
+		t.Fatalf("loadConfig failed: %v", err)
+	}
+	config := loaded.Config
+
+	// Later files win, mappings are merged per key and lists are replaced
+	if config.Source.Recursive || config.Destination.Width != 1280 || config.Destination.Height != 500 {
+		t.Errorf("Unexpected values: %+v, %+v", config.Source, config.Destination)
+	}
+	if len(config.Source.Exclude) != 1 || config.Source.Exclude[0] != "c" {
+		t.Errorf("Expected the list to be replaced, got %v", config.Source.Exclude)
+	}
+
+	wantFiles := []string{
+		filepath.Join(dir, "common", "base.yml"),
+		filepath.Join(dir, "extra.yml"),
+		path,
+		filepath.Join(dir, "config.local.yml"),
+	}
+	if strings.Join(loaded.Files, "\n") != strings.Join(wantFiles, "\n") {
+		t.Errorf("Expected files %v, got %v", wantFiles, loaded.Files)
+	}
+
+	t.Setenv("DESTINATION_WIDTH", "1920")
+	loaded, _ = loadConfig(path, true, nil)
+	for key, want := range map[string]string{
+		"source.recursive":   filepath.Join(dir, "common", "base.yml") + ":2",
+		"source.exclude":     filepath.Join(dir, "extra.yml") + ":2",
+		"destination.width":  "DESTINATION_WIDTH (environment variable)",
+		"destination.height": filepath.Join(dir, "config.local.yml") + ":2",
+		"log.level":          "default",
+		"source.min_width":   "not set",
+	} {
+		if got := loaded.Origins[key]; got != want {
+			t.Errorf("Expected the origin of %s to be %q, got %q", key, want, got)
+		}
+	}
+}
+
+func TestLoadConfigLayerProblems(t *testing.T) {
+	dir := writeLayerFiles(t, map[string]string{
+		"config.yml": "include: [a.yml, missing.yml]\n",
+		"a.yml":      "include: b.yml\n",
+		"b.yml":      "include: a.yml\n",
+	})
+	problems := loadConfigProblems(t, filepath.Join(dir, "config.yml"))
+	if len(problems) != 2 {
+		t.Fatalf("Expected 2 problems, got %+v", problems)
+	}
+	if cycle := problems[0]; cycle.File != filepath.Join(dir, "b.yml") || !strings.HasPrefix(cycle.Message, "include cycle") {
+		t.Errorf("Expected an include cycle in b.yml, got %+v", cycle)
+	}
+	if missing := problems[1]; missing.Field != "include[1]" || missing.Line != 1 || !strings.Contains(missing.Message, "does not exist") {
+		t.Errorf("Expected a missing include on line 1, got %+v", missing)
+	}
+
+	// Problems in included files are reported with the file and line where the value is written
+	dir = writeLayerFiles(t, map[string]string{
+		"config.yml": "include: [base.yml]\nsource:\n  recursive: false\n",
+		"base.yml":   "source:\n  recursive: true\ndestination:\n  width: -1\n  height: abc\n",
+	})
+	problems = loadConfigProblems(t, filepath.Join(dir, "config.yml"))
+	base := filepath.Join(dir, "base.yml")
+	for _, want := range []struct {
+		field string
+		line  int
+	}{
+		{"destination.width", 4},
+		{"destination.height", 5},
+	} {
+		found := false
+		for _, problem := range problems {
+			if problem.Field == want.field {
+				found = true
+				if problem.File != base || problem.Line != want.line {
+					t.Errorf("Expected %s at %s:%d, got %+v", want.field, base, want.line, problem)
+				}
+			}
+		}
+		if !found {
+			t.Errorf("Expected a problem for %s, got %+v", want.field, problems)
+		}
+	}
+}
+
+func TestRunConfigShow(t *testing.T) {
+	t.Setenv("CONFIG_PATH", "")
+	t.Setenv("DESTINATION_WIDTH", "")
+	source := t.TempDir()
+	destination := t.TempDir()
+	if err := os.Mkdir(filepath.Join(destination, "EasyAntiCheat"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	dir := writeLayerFiles(t, map[string]string{
+		"config.yml":       "include: [base.yml]\nsource:\n  path: " + source + "\n",
+		"base.yml":         "destination:\n  path: " + destination + "\n  width: 640\n",
+		"config.local.yml": "destination:\n  width: 1280\n",
+	})
+	path := filepath.Join(dir, "config.yml")
+
+	var out bytes.Buffer
+	if err := runCLI([]string{"config", "show", "-config", path}, &out); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "width: 1280\n") || strings.Contains(out.String(), "include") || strings.Contains(out.String(), "log:") {
+		t.Errorf("Expected only the merged files in the output:\n%s", out.String())
+	}
+
+	out.Reset()
+	if err := runCLI([]string{"config", "show", "-effective", "-config", path}, &out); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"# " + filepath.Join(dir, "base.yml") + "\n",
+		"width: 1280 # " + filepath.Join(dir, "config.local.yml") + ":2\n",
+		"height: 450 # default\n",
+		"level: info # default\n",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("Expected %q in the output:\n%s", want, out.String())
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// アプリケーションの設定フォルダの名前（$XDG_CONFIG_HOME/splashscreen-changer など）
+const configDirName = "splashscreen-changer"
+
+// 設定フォルダで探す設定ファイルの名前。同じフォルダに複数ある場合は、この順に優先する
+var configFileNames = []string{"config.yml", "config.yaml", "config.json", "config.toml"}
+
+// configCandidate は、設定ファイルを探す場所の 1 つです。
+type configCandidate struct {
+	Path string `json:"path"`
+	// Reason は、この場所を探す理由（例: "-config argument"、"XDG_CONFIG_HOME"）
+	Reason string `json:"reason"`
+	Exists bool   `json:"exists"`
+	// Explicit は、引数または環境変数で指定された場所か。指定された場所は、存在しなくても使用する
+	Explicit bool `json:"-"`
+}
+
+// configSearchPaths は、設定ファイルを探す場所を優先順に返します。
+// flagValue は -config 引数の値、goos は実行環境の OS（runtime.GOOS）です。
+//
+//  1. -config 引数
+//  2. 環境変数 CONFIG_PATH
+//  3. 実行ファイルと同じフォルダの data フォルダ（go run の場合はカレントディレクトリの data フォルダ）
+//  4. カレントディレクトリ
+//  5. $XDG_CONFIG_HOME/splashscreen-changer（XDG_CONFIG_HOME が空の場合、Windows 以外では ~/.config/splashscreen-changer）
+//  6. %APPDATA%\splashscreen-changer（Windows のみ）
+//  7. /etc/splashscreen-changer（Windows 以外のみ）
+//
+// 3 以降の各フォルダでは、configFileNames の名前の設定ファイルを探します。
+func configSearchPaths(flagValue, goos string) []configCandidate {
+	var candidates []configCandidate
+	if flagValue != "" {
+		candidates = append(candidates, configCandidate{Path: flagValue, Reason: "-config argument", Explicit: true})
+	}
+	if envValue := os.Getenv("CONFIG_PATH"); envValue != "" {
+		candidates = append(candidates, configCandidate{Path: envValue, Reason: "CONFIG_PATH environment variable", Explicit: true})
+	}
+
+	addDir := func(dir, reason string) {
+		for _, name := range configFileNames {
+			candidates = append(candidates, configCandidate{Path: filepath.Join(dir, name), Reason: reason})
+		}
+	}
+	addDir(filepath.Dir(getDataFilePath("config.yml")), "data folder of the application")
+	if cwd, err := os.Getwd(); err == nil {
+		addDir(cwd, "current directory")
+	}
+	if xdg := os.Getenv("XDG_CONFIG_HOME"); xdg != "" {
+		addDir(filepath.Join(xdg, configDirName), "XDG_CONFIG_HOME")
+	} else if home, err := os.UserHomeDir(); err == nil && goos != "windows" {
+		addDir(filepath.Join(home, ".config", configDirName), "~/.config (XDG_CONFIG_HOME is not set)")
+	}
+	if appData := os.Getenv("APPDATA"); appData != "" && goos == "windows" {
+		addDir(filepath.Join(appData, configDirName), "APPDATA")
+	}
+	if goos != "windows" {
+		addDir(filepath.Join("/etc", configDirName), "system-wide configuration")
+	}
+
+	for i := range candidates {
+		info, err := os.Stat(candidates[i].Path)
+		candidates[i].Exists = err == nil && !info.IsDir()
+	}
+	return candidates
+}
+
+// findConfigFile は、設定ファイルを探し、使用する設定ファイルと、探した場所の一覧を返します。
+// 引数または環境変数で指定された場合は、存在しなくてもそのパスを使用します。
+// どこにも存在しない場合は、data フォルダの config.yml を使用します（設定ファイルなしで環境変数のみで設定する場合）。
+func findConfigFile(flagValue string) (configCandidate, []configCandidate) {
+	candidates := configSearchPaths(flagValue, runtime.GOOS)
+	for _, candidate := range candidates {
+		if candidate.Explicit || candidate.Exists {
+			return candidate, candidates
+		}
+	}
+	return configCandidate{Path: getDataFilePath("config.yml"), Reason: "default location (no configuration file was found)"}, candidates
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFindConfigFile(t *testing.T) {
+	cwd := t.TempDir()
+	t.Chdir(cwd)
+	xdg := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", xdg)
+	t.Setenv("CONFIG_PATH", "")
+
+	found, _ := findConfigFile("")
+	if found.Exists || !strings.HasPrefix(found.Reason, "default location") {
+		t.Errorf("Expected the default location when no file exists, got %+v", found)
+	}
+
+	xdgConfig := filepath.Join(xdg, configDirName, "config.json")
+	if err := os.MkdirAll(filepath.Dir(xdgConfig), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(xdgConfig, []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if found, _ = findConfigFile(""); found.Path != xdgConfig || found.Reason != "XDG_CONFIG_HOME" {
+		t.Errorf("Expected %s from XDG_CONFIG_HOME, got %+v", xdgConfig, found)
+	}
+
+	// The current directory has priority over XDG_CONFIG_HOME
+	if err := os.WriteFile(filepath.Join(cwd, "config.toml"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if found, _ = findConfigFile(""); found.Reason != "current directory" || filepath.Base(found.Path) != "config.toml" {
+		t.Errorf("Expected config.toml in the current directory, got %+v", found)
+	}
+
+	// Explicit paths are used even if they do not exist, and -config has priority over CONFIG_PATH
+	t.Setenv("CONFIG_PATH", "missing.yml")
+	if found, _ = findConfigFile(""); found.Path != "missing.yml" || found.Exists {
+		t.Errorf("Expected CONFIG_PATH to be used, got %+v", found)
+	}
+	if found, _ = findConfigFile("other.yml"); found.Path != "other.yml" || found.Reason != "-config argument" {
+		t.Errorf("Expected the -config argument to be used, got %+v", found)
+	}
+}
+
+func TestConfigSearchPathsByOS(t *testing.T) {
+	t.Setenv("CONFIG_PATH", "")
+	t.Setenv("XDG_CONFIG_HOME", "")
+	t.Setenv("APPDATA", filepath.Join("C:", "Users", "Name", "AppData", "Roaming"))
+
+	reasons := func(goos string) map[string]bool {
+		found := map[string]bool{}
+		for _, candidate := range configSearchPaths("", goos) {
+			found[candidate.Reason] = true
+		}
+		return found
+	}
+
+	windows := reasons("windows")
+	if !windows["APPDATA"] || windows["system-wide configuration"] || windows["~/.config (XDG_CONFIG_HOME is not set)"] {
+		t.Errorf("Unexpected locations on Windows: %v", windows)
+	}
+	linux := reasons("linux")
+	if linux["APPDATA"] || !linux["system-wide configuration"] || !linux["~/.config (XDG_CONFIG_HOME is not set)"] {
+		t.Errorf("Unexpected locations on Linux: %v", linux)
+	}
+}
+
+func TestRunConfigPath(t *testing.T) {
+	t.Chdir(t.TempDir())
+	t.Setenv("CONFIG_PATH", "")
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	if err := os.WriteFile("config.yml", nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	if err := runCLI([]string{"config", "path"}, &out); err != nil {
+		t.Fatal(err)
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"Configuration file: " + filepath.Join(cwd, "config.yml"),
+		"Reason: current directory",
+		"  * " + filepath.Join(cwd, "config.yml") + " (current directory)",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("Expected %q in the output:\n%s", want, out.String())
+		}
+	}
+}
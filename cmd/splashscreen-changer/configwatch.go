@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// エディターは保存時に複数のイベント（書き込み、名前の変更など）を発生させるため、最後のイベントからこの時間待ってから読み込み直す
+const configReloadDelay = 300 * time.Millisecond
+
+// configWatcher は、設定ファイルの変更を監視し、変更された場合に読み込み直します。
+// 読み込み直した設定に問題がない場合のみ、使用する設定を入れ替えます。問題がある場合は、ログに出力して前の設定を使い続けます。
+type configWatcher struct {
+	// path は、設定ファイルのパス
+	path string
+	// load は、設定ファイルを読み込み、チェックする関数（コマンドラインの引数を含め、起動時と同じ方法で読み込む）
+	load    func() (*loadedConfig, error)
+	current atomic.Pointer[loadedConfig]
+
+	// watching は、watch が監視を開始したときに呼び出される関数（テスト用。nil の場合は呼び出さない）
+	watching func()
+	// reloaded は、watch が設定ファイルを読み込み直したときに、その結果を渡して呼び出される関数（テスト用。nil の場合は呼び出さない）
+	reloaded func(error)
+}
+
+// newConfigWatcher は、起動時に読み込んだ設定 loaded を使用する configWatcher を作成します。
+func newConfigWatcher(path string, loaded *loadedConfig, load func() (*loadedConfig, error)) *configWatcher {
+	w := &configWatcher{path: path, load: load}
+	w.current.Store(loaded)
+	return w
+}
+
+// Loaded は、現在使用する設定を返します。複数の goroutine から呼び出せます。
+func (w *configWatcher) Loaded() *loadedConfig {
+	return w.current.Load()
+}
+
+// reload は、設定ファイルを読み込み直し、問題がない場合のみ使用する設定を入れ替えます。
+func (w *configWatcher) reload() error {
+	loaded, err := w.load()
+	if err != nil {
+		var configErr *configError
+		if errors.As(err, &configErr) {
+			for _, problem := range configErr.Problems {
+				slog.Error("Problem in the configuration file", "location", problem.location(w.path), "problem", problem.Message)
+			}
+		}
+		slog.Error("Failed to reload the configuration file, keeping the previous configuration", "path", w.path, "error", err)
+		return err
+	}
+
+	w.current.Store(loaded)
+	for _, warning := range loaded.Warnings {
+		slog.Warn("Problem in the configuration file", "location", warning.location(w.path), "problem", warning.Message)
+	}
+	slog.Info("Reloaded the configuration file", "path", w.path)
+	return nil
+}
+
+// watchedFiles は、監視する設定ファイルの絶対パスを返します。
+// 存在しない設定ファイルや config.local.yml も、作成された場合に読み込むよう監視します。
+func (w *configWatcher) watchedFiles() map[string]bool {
+	files := map[string]bool{}
+	paths := append([]string{w.path, localConfigPath(w.path)}, w.Loaded().Files...)
+	for _, path := range paths {
+		if abs, err := filepath.Abs(path); err == nil {
+			files[abs] = true
+		}
+	}
+	return files
+}
+
+// watch は、ctx が終了するまで設定ファイルを監視します。
+// エディターは設定ファイルを別のファイルに保存してから置き換える場合があるため、設定ファイルではなく、そのフォルダを監視します。
+func (w *configWatcher) watch(ctx context.Context) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+
+	files := w.watchedFiles()
+	watchDirs := func() {
+		watching := map[string]bool{}
+		for _, dir := range watcher.WatchList() {
+			watching[dir] = true
+		}
+		for file := range files {
+			dir := filepath.Dir(file)
+			if watching[dir] {
+				continue
+			}
+			if err := watcher.Add(dir); err != nil {
+				slog.Warn("Failed to watch the configuration folder", "path", dir, "error", err)
+				continue
+			}
+			watching[dir] = true
+		}
+	}
+	watchDirs()
+	slog.Info("Watching the configuration file for changes", "path", w.path)
+	if w.watching != nil {
+		w.watching()
+	}
+
+	var reload <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if event.Op == fsnotify.Chmod || !files[filepath.Clean(event.Name)] {
+				continue
+			}
+			slog.Debug("Configuration file changed", "path", event.Name, "op", event.Op.String())
+			reload = time.After(configReloadDelay)
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			slog.Warn("Error while watching the configuration file", "error", err)
+		case <-reload:
+			reload = nil
+			err := w.reload()
+			if err == nil {
+				// include で指定したファイルが変わった場合は、監視するファイルも変わる
+				files = w.watchedFiles()
+				watchDirs()
+			}
+			if w.reloaded != nil {
+				w.reloaded(err)
+			}
+		}
+	}
+}
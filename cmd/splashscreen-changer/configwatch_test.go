@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestConfigWatcher loads the config file and returns a watcher that reloads it the same way.
+func newTestConfigWatcher(t *testing.T, path string) *configWatcher {
+	t.Helper()
+	load := func() (*loadedConfig, error) { return loadConfig(path, false, nil) }
+	loaded, err := load()
+	if err != nil {
+		t.Fatalf("loadConfig failed: %v", err)
+	}
+	return newConfigWatcher(path, loaded, load)
+}
+
+// replaceInFile replaces old with new in the file.
+func replaceInFile(t *testing.T, path, old, new string) {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(strings.Replace(string(data), old, new, 1)), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestConfigWatcherReload(t *testing.T) {
+	t.Setenv("DESTINATION_WIDTH", "")
+	path := writeExplicitConfig(t, "", "  width: 1280\n")
+	watcher := newTestConfigWatcher(t, path)
+
+	// Invalid configurations are not applied
+	replaceInFile(t, path, "width: 1280", "width: -1")
+	if err := watcher.reload(); err == nil {
+		t.Error("Expected an error for an invalid width")
+	}
+	if width := watcher.Loaded().Config.Destination.Width; width != 1280 {
+		t.Errorf("Expected the previous configuration to stay active, got width %d", width)
+	}
+
+	replaceInFile(t, path, "width: -1", "width: 1920")
+	if err := watcher.reload(); err != nil {
+		t.Fatalf("reload failed: %v", err)
+	}
+	if width := watcher.Loaded().Config.Destination.Width; width != 1920 {
+		t.Errorf("Expected the reloaded width 1920, got %d", width)
+	}
+}
+
+func TestConfigWatcherWatch(t *testing.T) {
+	t.Setenv("DESTINATION_WIDTH", "")
+	path := writeExplicitConfig(t, "", "  width: 1280\n")
+	watcher := newTestConfigWatcher(t, path)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	started := make(chan struct{})
+	reloads := make(chan error)
+	watcher.watching = func() { close(started) }
+	watcher.reloaded = func(err error) {
+		select {
+		case reloads <- err:
+		case <-ctx.Done():
+		}
+	}
+	done := make(chan error)
+	go func() { done <- watcher.watch(ctx) }()
+	defer func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("watch failed: %v", err)
+		}
+	}()
+
+	// waitForWidth waits for reloads until the width becomes want
+	waitForWidth := func(want int) {
+		t.Helper()
+		timeout := time.After(5 * time.Second)
+		for watcher.Loaded().Config.Destination.Width != want {
+			select {
+			case err := <-reloads:
+				if err != nil {
+					t.Fatalf("reload failed: %v", err)
+				}
+			case <-timeout:
+				t.Fatalf("Expected width %d, got %d", want, watcher.Loaded().Config.Destination.Width)
+			}
+		}
+	}
+
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("The watcher did not start")
+	}
+	replaceInFile(t, path, "width: 1280", "width: 1920")
+	waitForWidth(1920)
+
+	// config.local.yml is picked up when it is created, including by renaming like editors do
+	temp := filepath.Join(filepath.Dir(path), "config.local.yml.tmp")
+	if err := os.WriteFile(temp, []byte("destination:\n  width: 640\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(temp, localConfigPath(path)); err != nil {
+		t.Fatal(err)
+	}
+	waitForWidth(640)
+}
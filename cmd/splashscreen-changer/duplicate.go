@@ -0,0 +1,245 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"log/slog"
+	"math/bits"
+	"os"
+
+	"golang.org/x/image/draw"
+)
+
+// dHash の計算に使用する縮小後の画像サイズ。横方向に隣り合う画素を比較するため、横幅は 1 画素多い
+const (
+	dHashWidth  = 9
+	dHashHeight = 8
+)
+
+// computeDHash は、画像の dHash（差分ハッシュ）を計算します。
+// 画像を 9x8 のグレースケールに縮小し、横方向に隣り合う画素の明るさを比較した 64 ビットの値を返します。
+// 見た目が似ている画像ほど、ハッシュのハミング距離が小さくなります。
+func computeDHash(path string) (uint64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	// 巨大な画像でもメモリ使用量を抑えられるよう、縮小しながら読み込む
+	img, err := decodePNGScaled(f, image.Rectangle{}, dHashWidth, dHashHeight)
+	if errors.Is(err, errPNGInterlaced) {
+		// インターレースされた PNG は通常の方法でデコードするが、画像全体をメモリに展開するため、大きすぎる場合はデコードしない
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return 0, err
+		}
+		config, err := png.DecodeConfig(f)
+		if err != nil {
+			return 0, err
+		}
+		if pixels := int64(config.Width) * int64(config.Height); pixels > largeImagePixels {
+			return 0, fmt.Errorf("image is interlaced and too large (%dx%d, %d pixels exceeds the limit of %d pixels for interlaced images)", config.Width, config.Height, pixels, largeImagePixels)
+		}
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return 0, err
+		}
+		img, err = png.Decode(f)
+		if err != nil {
+			return 0, err
+		}
+	} else if err != nil {
+		return 0, err
+	}
+
+	// 元の画像が 9x8 より小さい場合や、通常のデコードを行った場合は 9x8 に拡大・縮小する
+	if bounds := img.Bounds(); bounds.Dx() != dHashWidth || bounds.Dy() != dHashHeight {
+		scaled := image.NewRGBA(image.Rect(0, 0, dHashWidth, dHashHeight))
+		draw.ApproxBiLinear.Scale(scaled, scaled.Rect, img, bounds, draw.Src, nil)
+		img = scaled
+	}
+
+	var hash uint64
+	for y := 0; y < dHashHeight; y++ {
+		for x := 0; x < dHashWidth-1; x++ {
+			hash <<= 1
+			if luminance(img, x, y) < luminance(img, x+1, y) {
+				hash |= 1
+			}
+		}
+	}
+	return hash, nil
+}
+
+// luminance は、画素の明るさを返します。
+func luminance(img image.Image, x, y int) uint32 {
+	r, g, b, _ := img.At(x, y).RGBA()
+	return (299*r + 587*g + 114*b) / 1000
+}
+
+// hammingDistance は、2 つのハッシュの異なるビットの数を返します。
+func hammingDistance(a, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
+
+// bkTree は、ハミング距離が一定以下のハッシュを高速に検索するための BK 木です。
+type bkTree struct {
+	root *bkNode
+}
+
+type bkNode struct {
+	hash     uint64
+	items    []int
+	children map[int]*bkNode
+}
+
+// add は、ハッシュとそれに対応する要素のインデックスを追加します。
+func (t *bkTree) add(hash uint64, item int) {
+	if t.root == nil {
+		t.root = &bkNode{hash: hash, items: []int{item}}
+		return
+	}
+
+	node := t.root
+	for {
+		distance := hammingDistance(node.hash, hash)
+		if distance == 0 {
+			node.items = append(node.items, item)
+			return
+		}
+		child, ok := node.children[distance]
+		if !ok {
+			if node.children == nil {
+				node.children = map[int]*bkNode{}
+			}
+			node.children[distance] = &bkNode{hash: hash, items: []int{item}}
+			return
+		}
+		node = child
+	}
+}
+
+// search は、ハミング距離が threshold 以下のハッシュに対応する要素のインデックスを返します。
+func (t *bkTree) search(hash uint64, threshold int) []int {
+	var found []int
+	stack := []*bkNode{}
+	if t.root != nil {
+		stack = append(stack, t.root)
+	}
+	for len(stack) > 0 {
+		node := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		distance := hammingDistance(node.hash, hash)
+		if distance <= threshold {
+			found = append(found, node.items...)
+		}
+		// 三角不等式により、距離が distance ± threshold の範囲の子だけを探索すればよい
+		for d, child := range node.children {
+			if d >= distance-threshold && d <= distance+threshold {
+				stack = append(stack, child)
+			}
+		}
+	}
+	return found
+}
+
+// groupDuplicates は、見た目がほぼ同じ画像をグループにまとめます。
+// ハッシュのハミング距離が threshold 以下の画像は同じグループとし、グループは連鎖的に結合されます。
+// ハッシュを計算できなかった画像は、それぞれ単独のグループになります。グループの順序は、候補の順序に従います。
+func groupDuplicates(candidates []candidate, index *libraryIndex, threshold int) [][]candidate {
+	hashes := make([]uint64, len(candidates))
+	hashed := make([]bool, len(candidates))
+	tree := &bkTree{}
+	for i, c := range candidates {
+		hash, err := index.dHash(c.Path)
+		if err != nil {
+			slog.Warn("Failed to compute image hash", "path", c.Path, "error", err)
+			continue
+		}
+		hashes[i] = hash
+		hashed[i] = true
+		tree.add(hash, i)
+	}
+
+	// Union-Find で、距離が近い画像同士を同じグループに結合する
+	parent := make([]int, len(candidates))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+	for i := range candidates {
+		if !hashed[i] {
+			continue
+		}
+		for _, j := range tree.search(hashes[i], threshold) {
+			if ri, rj := find(i), find(j); ri != rj {
+				parent[max(ri, rj)] = min(ri, rj)
+			}
+		}
+	}
+
+	var groups [][]candidate
+	groupIndex := map[int]int{}
+	for i, c := range candidates {
+		root := find(i)
+		gi, ok := groupIndex[root]
+		if !ok {
+			gi = len(groups)
+			groupIndex[root] = gi
+			groups = append(groups, nil)
+		}
+		groups[gi] = append(groups[gi], c)
+	}
+	return groups
+}
+
+// collapseDuplicates は、各グループからランダムに 1 つの画像を選び、グループごとに 1 つの候補にまとめます。
+// これにより、連写などで似た画像が多いシーンも、1 枚の画像と同じ確率で選択されるようになります。
+func collapseDuplicates(groups [][]candidate) []candidate {
+	candidates := make([]candidate, 0, len(groups))
+	for _, group := range groups {
+		if len(group) == 1 {
+			candidates = append(candidates, group[0])
+			continue
+		}
+
+		picked, err := pickRandomFile(candidatePaths(group))
+		if err != nil {
+			continue
+		}
+		for _, c := range group {
+			if c.Path == picked {
+				candidates = append(candidates, c)
+				break
+			}
+		}
+	}
+	return candidates
+}
+
+// printDuplicateGroups は、2 つ以上の画像を含むグループを表示します。
+func printDuplicateGroups(w io.Writer, groups [][]candidate) {
+	count := 0
+	for _, group := range groups {
+		if len(group) < 2 {
+			continue
+		}
+		count++
+		fmt.Fprintf(w, "Group %d (%d images):\n", count, len(group))
+		for _, c := range group {
+			fmt.Fprintf(w, "  %s\n", c.Path)
+		}
+	}
+	if count == 0 {
+		fmt.Fprintln(w, "No duplicate images found")
+	}
+}
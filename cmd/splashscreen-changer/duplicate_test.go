@@ -0,0 +1,176 @@
+package main
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// writeGradientPNG writes a horizontal gradient image. Direction and brightness offset change the content.
+func writeGradientPNG(t *testing.T, path string, width, height int, reverse bool, offset int) {
+	t.Helper()
+
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			v := x * 200 / width
+			if reverse {
+				v = 200 - v
+			}
+			// Add a vertical band so the hash has structure in both directions
+			if y > height/2 {
+				v = 200 - v
+			}
+			v = min(255, v+offset)
+			img.Set(x, y, color.RGBA{uint8(v), uint8(v), uint8(v), 0xff})
+		}
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+	defer f.Close()
+	if err := png.Encode(f, img); err != nil {
+		t.Fatalf("Failed to encode PNG: %v", err)
+	}
+}
+
+func TestComputeDHash(t *testing.T) {
+	tmpDir := t.TempDir()
+	original := filepath.Join(tmpDir, "original.png")
+	resized := filepath.Join(tmpDir, "resized.png")
+	brighter := filepath.Join(tmpDir, "brighter.png")
+	different := filepath.Join(tmpDir, "different.png")
+	writeGradientPNG(t, original, 320, 180, false, 0)
+	writeGradientPNG(t, resized, 160, 90, false, 0)
+	writeGradientPNG(t, brighter, 320, 180, false, 20)
+	writeGradientPNG(t, different, 320, 180, true, 0)
+
+	hashes := map[string]uint64{}
+	for _, path := range []string{original, resized, brighter, different} {
+		hash, err := computeDHash(path)
+		if err != nil {
+			t.Fatalf("Expected no error for %s, got %v", path, err)
+		}
+		hashes[path] = hash
+	}
+
+	if d := hammingDistance(hashes[original], hashes[resized]); d > 4 {
+		t.Errorf("Expected resized image to be near-duplicate, distance %d", d)
+	}
+	if d := hammingDistance(hashes[original], hashes[brighter]); d > 10 {
+		t.Errorf("Expected brighter image to be near-duplicate, distance %d", d)
+	}
+	if d := hammingDistance(hashes[original], hashes[different]); d <= 10 {
+		t.Errorf("Expected reversed image to differ, distance %d", d)
+	}
+
+	// Small interlaced images are decoded entirely, large ones are skipped
+	interlaced := filepath.Join(tmpDir, "interlaced.png")
+	writeInterlacedTestPNG(t, interlaced, 1, 1)
+	if _, err := computeDHash(interlaced); err != nil {
+		t.Errorf("Expected no error for a small interlaced image, got %v", err)
+	}
+	writeInterlacedTestPNG(t, interlaced, 5000, 5000)
+	if _, err := computeDHash(interlaced); err == nil || !strings.Contains(err.Error(), "too large") {
+		t.Errorf("Expected an error for a large interlaced image, got %v", err)
+	}
+
+	if _, err := computeDHash(filepath.Join(tmpDir, "missing.png")); err == nil {
+		t.Errorf("Expected an error for a missing file, got nil")
+	}
+}
+
+func TestBKTreeSearch(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	hashes := make([]uint64, 500)
+	tree := &bkTree{}
+	for i := range hashes {
+		hashes[i] = r.Uint64()
+		// Make some hashes close to others
+		if i%5 == 1 {
+			hashes[i] = hashes[i-1] ^ 1<<uint(r.Intn(64))
+		}
+		tree.add(hashes[i], i)
+	}
+
+	for _, threshold := range []int{0, 3, 20} {
+		for _, query := range hashes[:50] {
+			got := tree.search(query, threshold)
+			sort.Ints(got)
+
+			var want []int
+			for i, hash := range hashes {
+				if hammingDistance(query, hash) <= threshold {
+					want = append(want, i)
+				}
+			}
+			if len(got) != len(want) {
+				t.Fatalf("threshold %d: expected %v, got %v", threshold, want, got)
+			}
+			for i := range want {
+				if got[i] != want[i] {
+					t.Fatalf("threshold %d: expected %v, got %v", threshold, want, got)
+				}
+			}
+		}
+	}
+}
+
+func TestGroupDuplicates(t *testing.T) {
+	tmpDir := t.TempDir()
+	paths := []string{
+		filepath.Join(tmpDir, "burst1.png"),
+		filepath.Join(tmpDir, "other.png"),
+		filepath.Join(tmpDir, "burst2.png"),
+		filepath.Join(tmpDir, "broken.png"),
+	}
+	writeGradientPNG(t, paths[0], 320, 180, false, 0)
+	writeGradientPNG(t, paths[1], 320, 180, true, 0)
+	writeGradientPNG(t, paths[2], 320, 180, false, 10)
+	os.WriteFile(paths[3], []byte("not a png"), 0644)
+
+	var candidates []candidate
+	for _, path := range paths {
+		candidates = append(candidates, candidate{Path: path})
+	}
+
+	index, _ := loadLibraryIndex(filepath.Join(tmpDir, "library.json"))
+	groups := groupDuplicates(candidates, index, 10)
+	if len(groups) != 3 {
+		t.Fatalf("Expected 3 groups, got %d: %v", len(groups), groups)
+	}
+	if len(groups[0]) != 2 || groups[0][0].Path != paths[0] || groups[0][1].Path != paths[2] {
+		t.Errorf("Expected burst shots to be grouped, got %v", groups[0])
+	}
+
+	// Hashes are cached in the index
+	if entry := index.Files[paths[0]]; entry == nil || entry.DHash == nil {
+		t.Errorf("Expected hash to be cached in the index")
+	}
+
+	collapsed := collapseDuplicates(groups)
+	if len(collapsed) != 3 {
+		t.Errorf("Expected 3 collapsed candidates, got %d", len(collapsed))
+	}
+
+	var buf bytes.Buffer
+	printDuplicateGroups(&buf, groups)
+	if !strings.Contains(buf.String(), "Group 1 (2 images):") || strings.Contains(buf.String(), "Group 2") {
+		t.Errorf("Unexpected report:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	printDuplicateGroups(&buf, groupDuplicates(candidates, index, 0)[1:2])
+	if !strings.Contains(buf.String(), "No duplicate images found") {
+		t.Errorf("Unexpected report:\n%s", buf.String())
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+)
+
+// 画像の向きの指定
+const (
+	orientationAny       = "any"
+	orientationLandscape = "landscape"
+	orientationPortrait  = "portrait"
+)
+
+// dimensionFilter は、画像サイズによるソース画像の絞り込み条件です。
+// 0 または空の値が設定された条件は使用しません。
+type dimensionFilter struct {
+	MinWidth    int
+	MinHeight   int
+	MinAspect   float64
+	MaxAspect   float64
+	Orientation string
+}
+
+// newDimensionFilter は、設定値から絞り込み条件を作成します。
+func newDimensionFilter(config *Config) dimensionFilter {
+	return dimensionFilter{
+		MinWidth:    config.Source.MinWidth,
+		MinHeight:   config.Source.MinHeight,
+		MinAspect:   config.Source.MinAspect,
+		MaxAspect:   config.Source.MaxAspect,
+		Orientation: config.Source.Orientation,
+	}
+}
+
+// isEmpty は、絞り込み条件が 1 つも設定されていない場合に true を返します。
+func (f dimensionFilter) isEmpty() bool {
+	return f.MinWidth <= 0 && f.MinHeight <= 0 && f.MinAspect <= 0 && f.MaxAspect <= 0 &&
+		(f.Orientation == "" || f.Orientation == orientationAny)
+}
+
+// matches は、指定された大きさの画像が絞り込み条件を満たす場合に true を返します。
+func (f dimensionFilter) matches(width, height int) bool {
+	if width <= 0 || height <= 0 {
+		return false
+	}
+	if width < f.MinWidth || height < f.MinHeight {
+		return false
+	}
+
+	aspect := float64(width) / float64(height)
+	if f.MinAspect > 0 && aspect < f.MinAspect {
+		return false
+	}
+	if f.MaxAspect > 0 && aspect > f.MaxAspect {
+		return false
+	}
+
+	switch f.Orientation {
+	case orientationLandscape:
+		return width > height
+	case orientationPortrait:
+		return height > width
+	}
+	return true
+}
+
+// filterByDimensions は、画像サイズの絞り込み条件を満たすファイルのみを返します。
+// 画像サイズは画像のヘッダーのみを読み込んで取得し、インデックスに保存します。
+func filterByDimensions(candidates []candidate, filter dimensionFilter, index *libraryIndex) []candidate {
+	if filter.isEmpty() {
+		return candidates
+	}
+
+	var matched []candidate
+	for _, c := range candidates {
+		entry, err := index.entry(c.Path)
+		if err != nil {
+			slog.Warn("Skipping unreadable image", "path", c.Path, "error", err)
+			continue
+		}
+		if filter.matches(entry.Width, entry.Height) {
+			matched = append(matched, c)
+		}
+	}
+	return matched
+}
+
+// validateOrientation は、画像の向きの指定が正しいかを確認します。
+func validateOrientation(orientation string) error {
+	switch orientation {
+	case "", orientationAny, orientationLandscape, orientationPortrait:
+		return nil
+	}
+	return fmt.Errorf("source orientation must be one of %s, %s or %s, got '%s'", orientationAny, orientationLandscape, orientationPortrait, orientation)
+}
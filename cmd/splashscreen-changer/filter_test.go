@@ -0,0 +1,100 @@
+package main
+
+import (
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeTestPNG writes a blank PNG of the given size.
+func writeTestPNG(t *testing.T, path string, width, height int) {
+	t.Helper()
+
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+	defer f.Close()
+
+	if err := png.Encode(f, image.NewGray(image.Rect(0, 0, width, height))); err != nil {
+		t.Fatalf("Failed to encode PNG: %v", err)
+	}
+}
+
+func TestDimensionFilterMatches(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter dimensionFilter
+		width  int
+		height int
+		want   bool
+	}{
+		{"No filter", dimensionFilter{}, 10, 10, true},
+		{"Min width satisfied", dimensionFilter{MinWidth: 100}, 100, 10, true},
+		{"Min width not satisfied", dimensionFilter{MinWidth: 100}, 99, 10, false},
+		{"Min height not satisfied", dimensionFilter{MinHeight: 100}, 1000, 99, false},
+		{"Min aspect not satisfied", dimensionFilter{MinAspect: 1.5}, 100, 100, false},
+		{"Max aspect not satisfied", dimensionFilter{MaxAspect: 2.5}, 300, 100, false},
+		{"Aspect within range", dimensionFilter{MinAspect: 1.5, MaxAspect: 2.5}, 1920, 1080, true},
+		{"Landscape", dimensionFilter{Orientation: orientationLandscape}, 1920, 1080, true},
+		{"Landscape rejects portrait", dimensionFilter{Orientation: orientationLandscape}, 1080, 1920, false},
+		{"Portrait", dimensionFilter{Orientation: orientationPortrait}, 1080, 1920, true},
+		{"Portrait rejects square", dimensionFilter{Orientation: orientationPortrait}, 100, 100, false},
+		{"Any accepts square", dimensionFilter{Orientation: orientationAny}, 100, 100, true},
+		{"Invalid size", dimensionFilter{}, 0, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.filter.matches(tt.width, tt.height); got != tt.want {
+				t.Errorf("matches(%d, %d) = %v, want %v", tt.width, tt.height, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilterByDimensions(t *testing.T) {
+	tmpDir := t.TempDir()
+	landscape := filepath.Join(tmpDir, "landscape.png")
+	portrait := filepath.Join(tmpDir, "portrait.png")
+	icon := filepath.Join(tmpDir, "icon.png")
+	broken := filepath.Join(tmpDir, "broken.png")
+	writeTestPNG(t, landscape, 160, 90)
+	writeTestPNG(t, portrait, 90, 160)
+	writeTestPNG(t, icon, 16, 16)
+	os.WriteFile(broken, []byte("not a png"), 0644)
+
+	index, err := loadLibraryIndex(filepath.Join(tmpDir, "library.json"))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	files := []candidate{{Path: landscape}, {Path: portrait}, {Path: icon}, {Path: broken}}
+	got := filterByDimensions(files, dimensionFilter{MinWidth: 32, Orientation: orientationLandscape}, index)
+	if len(got) != 1 || got[0].Path != landscape {
+		t.Fatalf("Expected only %s, got %v", landscape, got)
+	}
+
+	// Readable images are cached in the index
+	if len(index.Files) != 3 {
+		t.Fatalf("Expected 3 cached entries, got %d", len(index.Files))
+	}
+
+	// An empty filter returns the input without reading the images
+	if got := filterByDimensions(files, dimensionFilter{Orientation: orientationAny}, index); len(got) != len(files) {
+		t.Fatalf("Expected %d files, got %d", len(files), len(got))
+	}
+}
+
+func TestValidateOrientation(t *testing.T) {
+	for _, orientation := range []string{"", "any", "landscape", "portrait"} {
+		if err := validateOrientation(orientation); err != nil {
+			t.Errorf("Expected no error for '%s', got %v", orientation, err)
+		}
+	}
+	if err := validateOrientation("square"); err == nil {
+		t.Errorf("Expected an error, got nil")
+	}
+}
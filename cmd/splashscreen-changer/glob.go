@@ -0,0 +1,127 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// pathFilter は、ソースフォルダを探索する際のパスの絞り込み条件です。
+// パターンはソースフォルダからの相対パス（区切り文字は "/"）に対して、大文字小文字を区別せずに評価します。
+type pathFilter struct {
+	// Include が空でない場合、いずれかのパターンに一致するファイルのみを対象とする
+	Include []string
+	// Exclude のいずれかのパターンに一致するファイル・フォルダは対象外とする。フォルダの場合は中身も探索しない
+	Exclude []string
+	// SkipHidden が true の場合、隠しファイル・隠しフォルダを対象外とする
+	SkipHidden bool
+}
+
+// newPathFilter は、設定値からパスの絞り込み条件を作成します。
+func newPathFilter(config *Config) pathFilter {
+	return pathFilter{
+		Include:    config.Source.Include,
+		Exclude:    config.Source.Exclude,
+		SkipHidden: config.Source.SkipHidden,
+	}
+}
+
+// validate は、パターンの書式が正しいかを確認します。
+func (f pathFilter) validate() error {
+	for _, patterns := range [][]string{f.Include, f.Exclude} {
+		for _, pattern := range patterns {
+			if _, err := matchGlob(pattern, ""); err != nil {
+				return fmt.Errorf("invalid glob pattern '%s': %w", pattern, err)
+			}
+		}
+	}
+	return nil
+}
+
+// skipDir は、指定されたフォルダを探索しない場合に true を返します。
+// - rel: ソースフォルダからの相対パス
+// - hidden: 隠しフォルダかどうか
+func (f pathFilter) skipDir(rel string, hidden bool) bool {
+	if f.SkipHidden && hidden {
+		return true
+	}
+	return matchAnyGlob(f.Exclude, rel)
+}
+
+// includeFile は、指定されたファイルを対象とする場合に true を返します。
+// - rel: ソースフォルダからの相対パス
+// - hidden: 隠しファイルかどうか
+func (f pathFilter) includeFile(rel string, hidden bool) bool {
+	if f.SkipHidden && hidden {
+		return false
+	}
+	if matchAnyGlob(f.Exclude, rel) {
+		return false
+	}
+	return len(f.Include) == 0 || matchAnyGlob(f.Include, rel)
+}
+
+// isHidden は、ファイル名が "." で始まるか、OS の隠し属性が付いている場合に true を返します。
+func isHidden(info os.FileInfo) bool {
+	return strings.HasPrefix(info.Name(), ".") || hasHiddenAttribute(info)
+}
+
+// matchAnyGlob は、相対パスがいずれかのパターンに一致する場合に true を返します。
+func matchAnyGlob(patterns []string, rel string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := matchGlob(pattern, rel); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// matchGlob は、相対パスがグロブパターンに一致するかを判定します。
+// "*" "?" "[...]" は path.Match と同様に 1 つの階層の中で一致し、"**" は 0 個以上の階層に一致します。
+// "/" を含まないパターンは、どの階層にある名前にも一致します（"thumbnails" は "**/thumbnails" と同じ）。
+func matchGlob(pattern, rel string) (bool, error) {
+	pattern = strings.ToLower(strings.Trim(filepath.ToSlash(pattern), "/"))
+	rel = strings.ToLower(filepath.ToSlash(rel))
+	if !strings.Contains(pattern, "/") && pattern != "**" {
+		pattern = "**/" + pattern
+	}
+
+	var names []string
+	if rel != "" && rel != "." {
+		names = strings.Split(rel, "/")
+	}
+	return matchGlobSegments(strings.Split(pattern, "/"), names)
+}
+
+func matchGlobSegments(patterns, names []string) (bool, error) {
+	for len(patterns) > 0 {
+		if patterns[0] == "**" {
+			// "**" の後ろのパターンが、残りの階層のどこかから一致するかを調べる
+			for i := 0; i <= len(names); i++ {
+				if ok, err := matchGlobSegments(patterns[1:], names[i:]); ok || err != nil {
+					return ok, err
+				}
+			}
+			return false, nil
+		}
+
+		if len(names) == 0 {
+			// 一致しない場合でも、残りのパターンの書式は確認する
+			for _, pattern := range patterns {
+				if _, err := path.Match(pattern, ""); err != nil {
+					return false, err
+				}
+			}
+			return false, nil
+		}
+
+		ok, err := path.Match(patterns[0], names[0])
+		if err != nil || !ok {
+			return false, err
+		}
+		patterns, names = patterns[1:], names[1:]
+	}
+	return len(names) == 0, nil
+}
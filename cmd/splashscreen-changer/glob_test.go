@@ -0,0 +1,56 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		pattern string
+		rel     string
+		want    bool
+	}{
+		{"*.png", "a.png", true},
+		{"*.png", "sub/a.png", true},
+		{"thumbnails", "thumbnails", true},
+		{"thumbnails", "2026/thumbnails", true},
+		{"thumbnails", "2026/thumbnails/a.png", false},
+		{"thumbnails/**", "2026/thumbnails/a.png", false},
+		{"**/thumbnails/**", "2026/thumbnails/a.png", true},
+		{"**/thumbnails/**", "2026/thumbnails", true},
+		{"private/**", "private", true},
+		{"private/**", "private/a/b/c.png", true},
+		{"private/**", "public/private/a.png", false},
+		{"2026-*/*.png", "2026-01/a.png", true},
+		{"2026-*/*.png", "2026-01/sub/a.png", false},
+		{"2026-*/**/*.png", "2026-01/sub/deeper/a.png", true},
+		{"2026-*/**/*.png", "2026-01/a.png", true},
+		{"**/edit_*.png", "a/b/edit_1.png", true},
+		{"**/edit_*.png", "a/b/1_edit.png", false},
+		{"**", "anything/at/all.png", true},
+		{"Events/**", "events/2026/a.png", true},
+		{"a/?/c.png", "a/b/c.png", true},
+		{"a/[bc]/d.png", "a/c/d.png", true},
+		{"a/[bc]/d.png", "a/e/d.png", false},
+	}
+
+	for _, tt := range tests {
+		got, err := matchGlob(tt.pattern, tt.rel)
+		if err != nil {
+			t.Errorf("matchGlob(%q, %q) returned error: %v", tt.pattern, tt.rel, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("matchGlob(%q, %q) = %v, want %v", tt.pattern, tt.rel, got, tt.want)
+		}
+	}
+}
+
+func TestPathFilterValidate(t *testing.T) {
+	if err := (pathFilter{Include: []string{"**/*.png"}, Exclude: []string{"private/**"}}).validate(); err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+	if err := (pathFilter{Exclude: []string{"a/[b/**"}}).validate(); err == nil {
+		t.Errorf("Expected an error for a malformed pattern, got nil")
+	}
+}
@@ -0,0 +1,11 @@
+//go:build !windows
+// +build !windows
+
+package main
+
+import "os"
+
+// hasHiddenAttribute は、Windows 以外では隠し属性がないため常に false を返します。
+func hasHiddenAttribute(_ os.FileInfo) bool {
+	return false
+}
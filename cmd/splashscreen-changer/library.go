@@ -0,0 +1,329 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"image"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ライブラリインデックスのファイル形式のバージョン。互換性のない変更を行った場合は値を増やす
+const libraryIndexVersion = 1
+
+// libraryIndex は、ソースフォルダの構成とソース画像のメタデータ（画像サイズ・ハッシュなど）を保存し、次回以降の実行で再利用するためのインデックスです。
+// ファイルのサイズと更新日時が変わっていない場合、画像を読み込まずに保存済みの値を使用します。
+// フォルダの更新日時が変わっていない場合、フォルダの中身を読み込まずに保存済みの一覧を使用します。
+type libraryIndex struct {
+	Version int                      `json:"version"`
+	Files   map[string]*libraryEntry `json:"files"`
+	Dirs    map[string]*libraryDir   `json:"dirs"`
+	// Tags は、tag コマンドで画像に付けたタグ。インデックスを作り直しても削除しない
+	Tags map[string][]string `json:"tags"`
+
+	path  string
+	dirty bool
+}
+
+// libraryDir は、1 つのフォルダの中身の一覧です。PNG ファイルとサブフォルダのみを保存します。
+// フォルダの更新日時は中身の追加・削除・名前の変更で更新されるため、更新日時が変わっていなければ一覧も変わっていないとみなします。
+type libraryDir struct {
+	ModTime time.Time         `json:"mod_time"`
+	Entries []libraryDirEntry `json:"entries"`
+}
+
+// libraryDirEntry は、フォルダの中の 1 つの PNG ファイルまたはサブフォルダです。
+type libraryDirEntry struct {
+	Name   string `json:"name"`
+	Dir    bool   `json:"dir,omitempty"`
+	Hidden bool   `json:"hidden,omitempty"`
+}
+
+// libraryEntry は、1 つのソース画像のメタデータです。
+type libraryEntry struct {
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+	Width   int       `json:"width"`
+	Height  int       `json:"height"`
+	// DHash は画像の dHash。まだ計算していない場合は nil
+	DHash *uint64 `json:"dhash,omitempty"`
+	// Screenshot は VRChat のスクリーンショットのメタデータ。まだ読み込んでいない場合は nil
+	Screenshot *screenshotMetadata `json:"screenshot,omitempty"`
+}
+
+// loadLibraryIndex は、指定されたパスからライブラリインデックスを読み込みます。
+// ファイルが存在しない場合や、バージョンが異なる場合は空のインデックスを返します。
+func loadLibraryIndex(path string) (*libraryIndex, error) {
+	index := newLibraryIndex(path)
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return index, nil
+	}
+	if err != nil {
+		return index, err
+	}
+
+	var loaded libraryIndex
+	if err := json.Unmarshal(data, &loaded); err != nil {
+		return index, err
+	}
+	if loaded.Version != libraryIndexVersion || loaded.Files == nil {
+		return index, nil
+	}
+
+	index.Files = loaded.Files
+	if loaded.Dirs != nil {
+		index.Dirs = loaded.Dirs
+	}
+	if loaded.Tags != nil {
+		index.Tags = loaded.Tags
+	}
+	return index, nil
+}
+
+// newLibraryIndex は、空のインデックスを作成します。path が空の場合、インデックスはファイルに保存されません。
+func newLibraryIndex(path string) *libraryIndex {
+	return &libraryIndex{
+		Version: libraryIndexVersion,
+		Files:   map[string]*libraryEntry{},
+		Dirs:    map[string]*libraryDir{},
+		Tags:    map[string][]string{},
+		path:    path,
+	}
+}
+
+// reset は、インデックスの内容を破棄します。次回の探索時に、すべてのフォルダとファイルを読み込み直します。
+// 画像に付けたタグは、ファイルから再取得できないため破棄しません。
+func (index *libraryIndex) reset() {
+	index.Files = map[string]*libraryEntry{}
+	index.Dirs = map[string]*libraryDir{}
+	index.dirty = true
+}
+
+// save は、インデックスに変更がある場合のみファイルに書き込みます。
+func (index *libraryIndex) save() error {
+	if !index.dirty || index.path == "" {
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(index.path), os.ModePerm); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(index)
+	if err != nil {
+		return err
+	}
+
+	// 書き込み途中で中断してもインデックスが壊れないよう、一時ファイルに書き込んでから置き換える
+	tmpPath := index.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, index.path); err != nil {
+		return err
+	}
+
+	index.dirty = false
+	return nil
+}
+
+// entry は、指定されたファイルのエントリを返します。
+// インデックスに保存されたエントリが古い場合は、画像のヘッダーを読み込んで更新します。
+func (index *libraryIndex) entry(path string) (*libraryEntry, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if entry, ok := index.Files[path]; ok && entry.Size == info.Size() && entry.ModTime.Equal(info.ModTime()) {
+		return entry, nil
+	}
+
+	width, height, err := readImageSize(path)
+	if err != nil {
+		return nil, err
+	}
+
+	entry := &libraryEntry{
+		Size:    info.Size(),
+		ModTime: info.ModTime(),
+		Width:   width,
+		Height:  height,
+	}
+	index.Files[path] = entry
+	index.dirty = true
+	return entry, nil
+}
+
+// dHash は、指定されたファイルの dHash を返します。
+// インデックスに保存されたハッシュがない場合や古い場合は、画像を読み込んで計算します。
+func (index *libraryIndex) dHash(path string) (uint64, error) {
+	entry, err := index.entry(path)
+	if err != nil {
+		return 0, err
+	}
+	if entry.DHash != nil {
+		return *entry.DHash, nil
+	}
+
+	hash, err := computeDHash(path)
+	if err != nil {
+		return 0, err
+	}
+	entry.DHash = &hash
+	index.dirty = true
+	return hash, nil
+}
+
+// readImageSize は、画像全体をデコードせずに画像の幅と高さを取得します。
+func readImageSize(path string) (int, int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer f.Close()
+
+	config, _, err := image.DecodeConfig(f)
+	if err != nil {
+		return 0, 0, err
+	}
+	return config.Width, config.Height, nil
+}
+
+// listPNGFiles は、指定されたディレクトリ以下の PNG ファイルをリストします。
+// 更新日時が変わっていないフォルダは、保存済みの一覧を使用するため、フォルダの中身を読み込みません。
+// filter に一致しないファイルは除外し、除外されたディレクトリの中は探索しません。
+func (index *libraryIndex) listPNGFiles(root string, isRecursive bool, filter pathFilter) ([]string, error) {
+	var pngFiles []string
+
+	var scan func(dir, rel string) error
+	scan = func(dir, rel string) error {
+		entries, err := index.readDir(dir)
+		if err != nil {
+			return err
+		}
+
+		for _, entry := range entries {
+			path := filepath.Join(dir, entry.Name)
+			entryRel := filepath.Join(rel, entry.Name)
+
+			if entry.Dir {
+				// 除外されたディレクトリは、中身も含めて探索しない
+				if !isRecursive || filter.skipDir(entryRel, entry.Hidden) {
+					continue
+				}
+				if err := scan(path, entryRel); err != nil {
+					return err
+				}
+				continue
+			}
+
+			if filter.includeFile(entryRel, entry.Hidden) {
+				pngFiles = append(pngFiles, path)
+			}
+		}
+		return nil
+	}
+
+	if err := scan(root, ""); err != nil {
+		return nil, err
+	}
+	return pngFiles, nil
+}
+
+// readDir は、フォルダの中の PNG ファイルとサブフォルダの一覧を返します。
+// フォルダの更新日時が保存済みの一覧と同じ場合は、保存済みの一覧を返します。
+func (index *libraryIndex) readDir(dir string) ([]libraryDirEntry, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	cached, ok := index.Dirs[dir]
+	if ok && cached.ModTime.Equal(info.ModTime()) {
+		return cached.Entries, nil
+	}
+
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []libraryDirEntry
+	for _, file := range files {
+		if !file.IsDir() && !strings.HasSuffix(strings.ToLower(file.Name()), ".png") {
+			continue
+		}
+
+		fileInfo, err := file.Info()
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, libraryDirEntry{
+			Name:   file.Name(),
+			Dir:    file.IsDir(),
+			Hidden: isHidden(fileInfo),
+		})
+	}
+
+	// なくなったファイル・フォルダの情報をインデックスから削除する
+	if ok {
+		index.forgetRemovedEntries(dir, cached.Entries, entries)
+	}
+
+	index.Dirs[dir] = &libraryDir{ModTime: info.ModTime(), Entries: entries}
+	index.dirty = true
+	return entries, nil
+}
+
+// forgetRemovedEntries は、以前の一覧にあり新しい一覧にないファイル・フォルダの情報をインデックスから削除します。
+func (index *libraryIndex) forgetRemovedEntries(dir string, previous, current []libraryDirEntry) {
+	exists := map[libraryDirEntry]bool{}
+	for _, entry := range current {
+		exists[libraryDirEntry{Name: entry.Name, Dir: entry.Dir}] = true
+	}
+
+	for _, entry := range previous {
+		if exists[libraryDirEntry{Name: entry.Name, Dir: entry.Dir}] {
+			continue
+		}
+
+		path := filepath.Join(dir, entry.Name)
+		if !entry.Dir {
+			delete(index.Files, path)
+			continue
+		}
+
+		// 削除されたフォルダ以下のすべてのフォルダ・ファイルを削除する
+		prefix := path + string(filepath.Separator)
+		for key := range index.Dirs {
+			if key == path || strings.HasPrefix(key, prefix) {
+				delete(index.Dirs, key)
+			}
+		}
+		for key := range index.Files {
+			if strings.HasPrefix(key, prefix) {
+				delete(index.Files, key)
+			}
+		}
+	}
+}
+
+// rebuild は、指定されたファイルの画像サイズ・ハッシュ・メタデータをすべて計算し直してインデックスに保存します。
+func (index *libraryIndex) rebuild(paths []string) {
+	for i, path := range paths {
+		if _, err := index.dHash(path); err != nil {
+			slog.Warn("Failed to index file", "path", path, "error", err)
+		} else if _, err := index.screenshot(path); err != nil {
+			slog.Warn("Failed to read screenshot metadata", "path", path, "error", err)
+		}
+		if (i+1)%1000 == 0 {
+			slog.Info("Indexing library", "indexed", i+1, "total", len(paths))
+		}
+	}
+}
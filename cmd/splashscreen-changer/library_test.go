@@ -0,0 +1,179 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLibraryIndexSaveAndLoad(t *testing.T) {
+	tmpDir := t.TempDir()
+	imagePath := filepath.Join(tmpDir, "image.png")
+	writeTestPNG(t, imagePath, 64, 32)
+	indexPath := filepath.Join(tmpDir, "data", "library.json")
+
+	index, err := loadLibraryIndex(indexPath)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	entry, err := index.entry(imagePath)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if entry.Width != 64 || entry.Height != 32 {
+		t.Fatalf("Expected 64x32, got %dx%d", entry.Width, entry.Height)
+	}
+	if err := index.save(); err != nil {
+		t.Fatalf("Failed to save index: %v", err)
+	}
+
+	loaded, err := loadLibraryIndex(indexPath)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	cached, ok := loaded.Files[imagePath]
+	if !ok {
+		t.Fatalf("Expected %s to be cached", imagePath)
+	}
+	if cached.Width != 64 || cached.Height != 32 {
+		t.Fatalf("Expected cached size 64x32, got %dx%d", cached.Width, cached.Height)
+	}
+}
+
+func TestLibraryIndexRefreshesStaleEntries(t *testing.T) {
+	tmpDir := t.TempDir()
+	imagePath := filepath.Join(tmpDir, "image.png")
+	writeTestPNG(t, imagePath, 64, 32)
+
+	index, _ := loadLibraryIndex(filepath.Join(tmpDir, "library.json"))
+	if _, err := index.entry(imagePath); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// Replace the image and move its modification time forward
+	writeTestPNG(t, imagePath, 20, 40)
+	future := time.Now().Add(time.Hour)
+	os.Chtimes(imagePath, future, future)
+
+	entry, err := index.entry(imagePath)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if entry.Width != 20 || entry.Height != 40 {
+		t.Fatalf("Expected refreshed size 20x40, got %dx%d", entry.Width, entry.Height)
+	}
+}
+
+func TestLoadLibraryIndexCorrupted(t *testing.T) {
+	indexPath := filepath.Join(t.TempDir(), "library.json")
+	os.WriteFile(indexPath, []byte("{broken"), 0644)
+
+	index, err := loadLibraryIndex(indexPath)
+	if err == nil {
+		t.Fatalf("Expected an error, got nil")
+	}
+	if index == nil || len(index.Files) != 0 {
+		t.Fatalf("Expected an empty index to be returned")
+	}
+}
+
+func TestLibraryIndexIncrementalScan(t *testing.T) {
+	tmpDir := t.TempDir()
+	subDir := filepath.Join(tmpDir, "sub")
+	os.MkdirAll(subDir, os.ModePerm)
+	writeTestPNG(t, filepath.Join(tmpDir, "a.png"), 8, 8)
+	writeTestPNG(t, filepath.Join(subDir, "b.png"), 8, 8)
+	indexPath := filepath.Join(t.TempDir(), "library.json")
+
+	index, _ := loadLibraryIndex(indexPath)
+	files, err := index.listPNGFiles(tmpDir, true, pathFilter{})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(files) != 2 {
+		t.Fatalf("Expected 2 files, got %v", files)
+	}
+	if err := index.save(); err != nil {
+		t.Fatalf("Failed to save index: %v", err)
+	}
+
+	// Add a file but restore the directory's modification time: the cached listing is used
+	info, _ := os.Stat(subDir)
+	writeTestPNG(t, filepath.Join(subDir, "c.png"), 8, 8)
+	os.Chtimes(subDir, info.ModTime(), info.ModTime())
+
+	index, _ = loadLibraryIndex(indexPath)
+	files, _ = index.listPNGFiles(tmpDir, true, pathFilter{})
+	if len(files) != 2 {
+		t.Fatalf("Expected the cached listing with 2 files, got %v", files)
+	}
+	if index.dirty {
+		t.Errorf("Expected index not to change when no directory changed")
+	}
+
+	// Once the directory changes, it is read again
+	future := time.Now().Add(time.Hour)
+	os.Chtimes(subDir, future, future)
+	files, _ = index.listPNGFiles(tmpDir, true, pathFilter{})
+	if len(files) != 3 {
+		t.Fatalf("Expected 3 files after rescan, got %v", files)
+	}
+
+	// A full rebuild ignores the cached listing
+	index.reset()
+	if len(index.Dirs) != 0 || len(index.Files) != 0 {
+		t.Fatalf("Expected reset to clear the index")
+	}
+	files, _ = index.listPNGFiles(tmpDir, false, pathFilter{})
+	if len(files) != 1 {
+		t.Fatalf("Expected 1 file in non-recursive mode, got %v", files)
+	}
+}
+
+func TestLibraryIndexForgetsRemovedEntries(t *testing.T) {
+	tmpDir := t.TempDir()
+	subDir := filepath.Join(tmpDir, "sub")
+	nestedDir := filepath.Join(subDir, "nested")
+	os.MkdirAll(nestedDir, os.ModePerm)
+	a := filepath.Join(tmpDir, "a.png")
+	b := filepath.Join(nestedDir, "b.png")
+	writeTestPNG(t, a, 8, 8)
+	writeTestPNG(t, b, 8, 8)
+
+	index := newLibraryIndex("")
+	files, _ := index.listPNGFiles(tmpDir, true, pathFilter{})
+	for _, file := range files {
+		index.entry(file)
+	}
+	if len(index.Files) != 2 || len(index.Dirs) != 3 {
+		t.Fatalf("Expected 2 files and 3 directories, got %d and %d", len(index.Files), len(index.Dirs))
+	}
+
+	os.Remove(a)
+	os.RemoveAll(subDir)
+	future := time.Now().Add(time.Hour)
+	os.Chtimes(tmpDir, future, future)
+
+	files, _ = index.listPNGFiles(tmpDir, true, pathFilter{})
+	if len(files) != 0 {
+		t.Fatalf("Expected no files, got %v", files)
+	}
+	if len(index.Files) != 0 || len(index.Dirs) != 1 {
+		t.Fatalf("Expected removed entries to be forgotten, got %d files and %d directories", len(index.Files), len(index.Dirs))
+	}
+}
+
+func TestLibraryIndexRebuild(t *testing.T) {
+	tmpDir := t.TempDir()
+	imagePath := filepath.Join(tmpDir, "image.png")
+	writeTestPNG(t, imagePath, 16, 9)
+
+	index := newLibraryIndex("")
+	index.rebuild([]string{imagePath, filepath.Join(tmpDir, "missing.png")})
+
+	entry, ok := index.Files[imagePath]
+	if !ok || entry.Width != 16 || entry.Height != 9 || entry.DHash == nil {
+		t.Fatalf("Expected size and hash to be indexed, got %+v", entry)
+	}
+}
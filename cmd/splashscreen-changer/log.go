@@ -1,30 +1,337 @@
 package main
 
 import (
+	"fmt"
+	"io"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 )
 
-func getLogFilePath(logParamPath *string) string {
+// 日付ごとのログファイルのファイル名の接頭辞。ログフォルダをほかのアプリケーションと共有しても、このアプリケーションのログファイルのみを削除する
+const dailyLogFilePrefix = "splashscreen-changer-"
+
+// 日付ごとのログファイルのファイル名（splashscreen-changer-yyyy-mm-dd.log、ローテーションしたファイルは splashscreen-changer-yyyy-mm-dd.1.log）
+var dailyLogFilePattern = regexp.MustCompile(`^` + regexp.QuoteMeta(dailyLogFilePrefix) + `\d{4}-\d{2}-\d{2}(\.\d+)?\.log$`)
+
+func getLogFilePath(logParamPath *string, now time.Time) string {
 	// ログフォルダパスは環境変数 LOG_PATH または引数 -log で指定し、指定されていない場合は "logs/" とする。
 	// "logs/" の場所は、実行ファイルと同じディレクトリにあるものとする。go runで実行する場合は、カレントディレクトリにあるものとする。
-	// ログファイルのファイル名は、yyyy-mm-dd.log とする。
+	// ログファイルのファイル名は、now の日付の splashscreen-changer-yyyy-mm-dd.log とする。
+	// 指定されたパスが既存のフォルダか、パス区切り文字で終わる場合は、そのフォルダに splashscreen-changer-yyyy-mm-dd.log を作成する。
+	name := dailyLogFilePrefix + now.Format("2006-01-02") + ".log"
+
 	if *logParamPath != "" {
+		if isDirPath(*logParamPath) {
+			return filepath.Join(*logParamPath, name)
+		}
 		return *logParamPath
 	}
 
-	date := time.Now().Format("2006-01-02")
-
 	exePath, err := os.Executable()
 	if err != nil {
-		return filepath.Join("logs", date+".log")
+		return filepath.Join("logs", name)
 	}
 
 	if isGoRun() {
-		return filepath.Join("logs", date+".log")
+		return filepath.Join("logs", name)
 	}
 
 	exeDir := filepath.Dir(exePath)
-	return filepath.Join(exeDir, "logs", date+".log")
+	return filepath.Join(exeDir, "logs", name)
+}
+
+// isDirPath は、パスがフォルダを指しているかを返します。
+func isDirPath(path string) bool {
+	if strings.HasSuffix(path, "/") || strings.HasSuffix(path, string(filepath.Separator)) {
+		return true
+	}
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+// parseLogLevel は、log.level の値を解析します。空の場合は info とします。
+func parseLogLevel(value string) (slog.Level, error) {
+	if value == "" {
+		return slog.LevelInfo, nil
+	}
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(value)); err != nil {
+		return 0, fmt.Errorf("invalid log level '%s' (expected debug, info, warn or error)", value)
+	}
+	return level, nil
+}
+
+// newLogHandler は、log.format に従って、ログを w に出力するハンドラーを作成します。
+func newLogHandler(w io.Writer, format string, level slog.Level) (slog.Handler, error) {
+	options := &slog.HandlerOptions{Level: level}
+	switch strings.ToLower(format) {
+	case "", "text":
+		return slog.NewTextHandler(w, options), nil
+	case "json":
+		return slog.NewJSONHandler(w, options), nil
+	}
+	return nil, fmt.Errorf("invalid log format '%s' (expected text or json)", format)
+}
+
+// setupLogger は、設定値に従ってログの出力先・レベル・形式を設定します。
+// file が nil でない場合は、console と file の両方に出力します。
+func setupLogger(config *Config, console io.Writer, file io.Writer) error {
+	level, err := parseLogLevel(config.Log.Level)
+	if err != nil {
+		return err
+	}
+	w := console
+	if file != nil {
+		w = io.MultiWriter(console, file)
+	}
+	handler, err := newLogHandler(w, config.Log.Format, level)
+	if err != nil {
+		return err
+	}
+	slog.SetDefault(slog.New(handler))
+	return nil
+}
+
+// rotatingFile は、一定の大きさを超えるとローテーションするログファイルです。
+// ローテーションしたファイルは、splashscreen-changer-yyyy-mm-dd.1.log のように番号を付けた名前に変更します。
+type rotatingFile struct {
+	path string
+	// maxSize は、ローテーションする大きさ（バイト）。0 以下の場合はローテーションしない
+	maxSize int64
+	file    *os.File
+	size    int64
+}
+
+// openRotatingFile は、ログファイルを追記モードで開きます。既に maxSize を超えている場合は、開く前にローテーションします。
+func openRotatingFile(path string, maxSize int64) (*rotatingFile, error) {
+	// ログファイルの親ディレクトリが存在しない場合は作成する
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return nil, err
+	}
+	f := &rotatingFile{path: path, maxSize: maxSize}
+	if info, err := os.Stat(path); err == nil && maxSize > 0 && info.Size() >= maxSize {
+		if err := os.Rename(path, rotatedLogFilePath(path)); err != nil {
+			return nil, err
+		}
+	}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *rotatingFile) open() error {
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	f.file = file
+	f.size = info.Size()
+	return nil
+}
+
+func (f *rotatingFile) Write(p []byte) (int, error) {
+	if f.maxSize > 0 && f.size > 0 && f.size+int64(len(p)) > f.maxSize {
+		if err := f.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+// rotate は、現在のログファイルの名前を変更し、新しいログファイルを開きます。
+func (f *rotatingFile) rotate() error {
+	if err := f.file.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(f.path, rotatedLogFilePath(f.path)); err != nil {
+		return err
+	}
+	return f.open()
+}
+
+func (f *rotatingFile) Close() error {
+	return f.file.Close()
+}
+
+// rotatedLogFilePath は、ローテーションしたログファイルの、まだ使われていないパスを返します（例: app.log → app.1.log）。
+func rotatedLogFilePath(path string) string {
+	ext := filepath.Ext(path)
+	stem := strings.TrimSuffix(path, ext)
+	for i := 1; ; i++ {
+		candidate := stem + "." + strconv.Itoa(i) + ext
+		if _, err := os.Stat(candidate); os.IsNotExist(err) {
+			return candidate
+		}
+	}
+}
+
+// isManagedLogFile は、ファイル名が current のログファイル、またはそのローテーションしたファイルかを返します。
+// current が日付ごとのログファイルの場合は、ほかの日付のログファイルも含みます。接頭辞のない日付のファイルは含みません。
+func isManagedLogFile(name, current string) bool {
+	if dailyLogFilePattern.MatchString(current) {
+		return dailyLogFilePattern.MatchString(name)
+	}
+	ext := filepath.Ext(current)
+	stem := strings.TrimSuffix(current, ext)
+	rotated := regexp.MustCompile(`^` + regexp.QuoteMeta(stem) + `\.\d+` + regexp.QuoteMeta(ext) + `$`)
+	return rotated.MatchString(name)
+}
+
+// cleanupLogFiles は、ログファイルと同じフォルダにある古いログファイルを削除し、削除したファイルのパスを返します。
+// 現在のログファイルは削除しません。
+func cleanupLogFiles(path string, maxAge time.Duration, now time.Time) ([]string, error) {
+	dir := filepath.Dir(path)
+	current := filepath.Base(path)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var removed []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || name == current || !isManagedLogFile(name, current) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || now.Sub(info.ModTime()) <= maxAge {
+			continue
+		}
+		target := filepath.Join(dir, name)
+		if err := os.Remove(target); err != nil {
+			return removed, err
+		}
+		removed = append(removed, target)
+	}
+	return removed, nil
+}
+
+// logFile は、ログを出力するログファイルです。
+// 日付ごとのログファイルを使用する場合、refresh を呼び出すと、日付が変わっていれば新しい日付のログファイルに切り替えます。
+type logFile struct {
+	// logPath は、log.path の値
+	logPath string
+	maxSize int64
+	// maxAge は、削除するログファイルの古さ。0 以下の場合は削除しない
+	maxAge time.Duration
+	// now は、現在の時刻を返す関数（テストでは固定の時刻を返す関数に置き換える）
+	now func() time.Time
+	// date は、最後にログファイルを開いた、または古いログファイルを削除した日付
+	date string
+
+	mu   sync.Mutex
+	file *rotatingFile
+}
+
+// newLogFile は、config の log の設定に従って、now の日付のログファイルを開きます。
+func newLogFile(config *Config, now func() time.Time) (*logFile, error) {
+	f := &logFile{
+		logPath: config.Log.Path,
+		maxSize: int64(config.Log.MaxSizeMB) * 1024 * 1024,
+		maxAge:  time.Duration(config.Log.MaxAgeDays) * 24 * time.Hour,
+		now:     now,
+	}
+	current := now()
+	file, err := openRotatingFile(getLogFilePath(&f.logPath, current), f.maxSize)
+	if err != nil {
+		return nil, err
+	}
+	f.file = file
+	f.date = current.Format("2006-01-02")
+	return f, nil
+}
+
+func (f *logFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.file.Write(p)
+}
+
+func (f *logFile) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.file.Close()
+}
+
+// path は、現在ログを出力しているログファイルのパスを返します。
+func (f *logFile) path() string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.file.path
+}
+
+// refresh は、日付が変わった場合に、新しい日付のログファイルに切り替え、古いログファイルを削除します。
+// 長時間実行する daemon コマンドで、画像を変更する前に呼び出します。同じ日付の間は何もしません。
+func (f *logFile) refresh() {
+	current := f.now()
+	date := current.Format("2006-01-02")
+	if date == f.date {
+		return
+	}
+	f.date = date
+
+	if path := getLogFilePath(&f.logPath, current); path != f.path() {
+		file, err := openRotatingFile(path, f.maxSize)
+		if err != nil {
+			slog.Warn("Failed to open the log file of the new day, keep writing to the previous one", "path", path, "error", err)
+		} else {
+			f.mu.Lock()
+			previous := f.file
+			f.file = file
+			f.mu.Unlock()
+			previous.Close()
+			slog.Info("Switched to the log file of the new day", "path", path)
+		}
+	}
+	f.cleanup(current)
+}
+
+// cleanup は、log.max_age_days より古いログファイルを削除します。
+func (f *logFile) cleanup(now time.Time) {
+	if f.maxAge <= 0 {
+		return
+	}
+	removed, err := cleanupLogFiles(f.path(), f.maxAge, now)
+	if err != nil {
+		slog.Warn("Failed to clean up old log files", "error", err)
+	}
+	for _, name := range removed {
+		slog.Debug("Removed old log file", "path", name)
+	}
+}
+
+// openLogFile は、ログファイルを開き、ログを標準出力とログファイルの両方に出力するよう設定します。
+// jsonOutput が true の場合は、標準出力を JSON の出力に使用するため、標準出力の代わりに標準エラー出力に出力します。
+// log.max_age_days より古いログファイルは削除します。
+func openLogFile(config *Config, jsonOutput bool) (*logFile, error) {
+	file, err := newLogFile(config, time.Now)
+	if err != nil {
+		return nil, err
+	}
+
+	console := io.Writer(os.Stdout)
+	if jsonOutput {
+		console = os.Stderr
+	}
+	if err := setupLogger(config, console, file); err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	file.cleanup(time.Now())
+	return file, nil
 }
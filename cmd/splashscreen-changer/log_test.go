@@ -0,0 +1,252 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseLogLevel(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    slog.Level
+		wantErr bool
+	}{
+		{"debug", slog.LevelDebug, false},
+		{"INFO", slog.LevelInfo, false},
+		{"warn", slog.LevelWarn, false},
+		{"error", slog.LevelError, false},
+		{"verbose", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := parseLogLevel(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseLogLevel(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			continue
+		}
+		if err == nil && got != tt.want {
+			t.Errorf("parseLogLevel(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestNewLogHandler(t *testing.T) {
+	var buf bytes.Buffer
+	handler, err := newLogHandler(&buf, "json", slog.LevelWarn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger := slog.New(handler)
+	logger.Info("Hidden message")
+	logger.Warn("Picked file", "path", "photo.png")
+
+	var record map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("Expected a single JSON record, got %q: %v", buf.String(), err)
+	}
+	if record["msg"] != "Picked file" || record["path"] != "photo.png" || record["level"] != "WARN" {
+		t.Errorf("Unexpected record: %v", record)
+	}
+
+	buf.Reset()
+	if handler, err = newLogHandler(&buf, "text", slog.LevelInfo); err != nil {
+		t.Fatal(err)
+	}
+	slog.New(handler).Info("Picked file", "path", "photo.png")
+	if !strings.Contains(buf.String(), `msg="Picked file" path=photo.png`) {
+		t.Errorf("Unexpected text record: %q", buf.String())
+	}
+
+	if _, err := newLogHandler(&buf, "xml", slog.LevelInfo); err == nil {
+		t.Error("Expected an error for an unknown format")
+	}
+}
+
+func TestGetLogFilePathDirectory(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.Local)
+	want := filepath.Join(dir, "splashscreen-changer-2026-10-19.log")
+	if got := getLogFilePath(&dir, now); got != want {
+		t.Errorf("Expected %s for a directory, got %s", want, got)
+	}
+
+	file := filepath.Join(dir, "app.log")
+	if got := getLogFilePath(&file, now); got != file {
+		t.Errorf("Expected %s for a file, got %s", file, got)
+	}
+}
+
+func TestRotatingFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "splashscreen-changer-2026-10-19.log")
+	f, err := openRotatingFile(path, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{"first\n", "second\n", "third\n"} {
+		if _, err := f.Write([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]string{
+		"splashscreen-changer-2026-10-19.1.log": "first\n",
+		"splashscreen-changer-2026-10-19.2.log": "second\n",
+		"splashscreen-changer-2026-10-19.log":   "third\n",
+	}
+	for name, want := range expected {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Errorf("Expected %s to exist: %v", name, err)
+			continue
+		}
+		if string(data) != want {
+			t.Errorf("Expected %s to contain %q, got %q", name, want, data)
+		}
+	}
+
+	// A file already larger than the limit is rotated when opened
+	f, err = openRotatingFile(filepath.Join(dir, "splashscreen-changer-2026-10-19.2.log"), 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	if _, err := os.Stat(filepath.Join(dir, "splashscreen-changer-2026-10-19.2.1.log")); err != nil {
+		t.Errorf("Expected the oversized file to be rotated: %v", err)
+	}
+}
+
+func TestCleanupLogFiles(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	files := map[string]time.Time{
+		"splashscreen-changer-2026-10-19.log":   now,
+		"splashscreen-changer-2026-10-18.log":   now.Add(-24 * time.Hour),
+		"splashscreen-changer-2026-09-01.log":   now.Add(-48 * 24 * time.Hour),
+		"splashscreen-changer-2026-09-01.1.log": now.Add(-48 * 24 * time.Hour),
+		// Files of other applications in the same folder are kept
+		"2026-09-01.log": now.Add(-48 * 24 * time.Hour),
+		"notes.txt":      now.Add(-48 * 24 * time.Hour),
+		"other.log":      now.Add(-48 * 24 * time.Hour),
+	}
+	for name, modTime := range files {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte("log"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	removed, err := cleanupLogFiles(filepath.Join(dir, "splashscreen-changer-2026-10-19.log"), 30*24*time.Hour, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(removed) != 2 {
+		t.Errorf("Expected 2 removed files, got %v", removed)
+	}
+	for name := range files {
+		_, err := os.Stat(filepath.Join(dir, name))
+		wantRemoved := name == "splashscreen-changer-2026-09-01.log" || name == "splashscreen-changer-2026-09-01.1.log"
+		if wantRemoved != os.IsNotExist(err) {
+			t.Errorf("Unexpected state of %s (removed: %t)", name, os.IsNotExist(err))
+		}
+	}
+}
+
+func TestCleanupLogFilesCustomName(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	old := now.Add(-48 * 24 * time.Hour)
+	for _, name := range []string{"app.log", "app.1.log", "2026-01-01.log"} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte("log"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, old, old); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Only the rotated files of app.log are removed, not the current file or unrelated logs
+	removed, err := cleanupLogFiles(filepath.Join(dir, "app.log"), 24*time.Hour, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(removed) != 1 || filepath.Base(removed[0]) != "app.1.log" {
+		t.Errorf("Expected only app.1.log to be removed, got %v", removed)
+	}
+}
+
+func TestLogFileRefresh(t *testing.T) {
+	// refresh logs the switch, so restore the default logger after the test
+	logger := slog.Default()
+	t.Cleanup(func() { slog.SetDefault(logger) })
+	slog.SetDefault(slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	dir := t.TempDir() + string(filepath.Separator)
+	now := time.Date(2026, 10, 19, 23, 0, 0, 0, time.Local)
+	// A log file of a day older than max_age_days
+	old := filepath.Join(dir, "splashscreen-changer-2026-10-01.log")
+	if err := os.WriteFile(old, []byte("old\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(old, now.AddDate(0, 0, -18), now.AddDate(0, 0, -18)); err != nil {
+		t.Fatal(err)
+	}
+
+	var config Config
+	config.Log.Path = dir
+	config.Log.MaxAgeDays = 18
+	f, err := newLogFile(&config, func() time.Time { return now })
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	write := func(line string) {
+		t.Helper()
+		f.refresh()
+		if _, err := f.Write([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("first\n")
+	now = now.Add(30 * time.Minute)
+	write("second\n")
+	// The old log file is kept until it becomes older than max_age_days on the next day
+	if _, err := os.Stat(old); err != nil {
+		t.Errorf("Expected %s to be kept on the same day: %v", old, err)
+	}
+
+	now = now.Add(time.Hour)
+	write("third\n")
+
+	expected := map[string]string{
+		"splashscreen-changer-2026-10-19.log": "first\nsecond\n",
+		"splashscreen-changer-2026-10-20.log": "third\n",
+	}
+	for name, want := range expected {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Errorf("Expected %s to exist: %v", name, err)
+			continue
+		}
+		if string(data) != want {
+			t.Errorf("Expected %s to contain %q, got %q", name, want, data)
+		}
+	}
+	if _, err := os.Stat(old); !os.IsNotExist(err) {
+		t.Errorf("Expected %s to be removed after the date changed", old)
+	}
+}
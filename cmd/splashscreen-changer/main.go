@@ -1,12 +1,13 @@
 package main
 
 import (
-	"flag"
+	"bytes"
+	"errors"
 	"fmt"
 	"image"
 	"image/png"
 	"io"
-	"log"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"strings"
@@ -17,47 +18,16 @@ import (
 )
 
 // 指定されたディレクトリ以下のすべてのPNGファイルをリストする関数
-func listPNGFiles(root string, isRecursive bool) ([]string, error) {
-	var pngFiles []string
-
-	if isRecursive {
-		// Walk関数で指定されたディレクトリ以下を再帰的に探索
-		err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
-			if err != nil {
-				return err
-			}
-
-			// ファイルで拡張子が.pngのものだけをリストに追加
-			if !info.IsDir() && strings.HasSuffix(strings.ToLower(info.Name()), ".png") {
-				pngFiles = append(pngFiles, path)
-			}
-			return nil
-		})
-
-		if err != nil {
-			return nil, err
-		}
-	} else {
-		// 指定されたディレクトリの直下のみを探索
-		files, err := os.ReadDir(root)
-		if err != nil {
-			return nil, err
-		}
-
-		for _, file := range files {
-			if !file.IsDir() && strings.HasSuffix(strings.ToLower(file.Name()), ".png") {
-				pngFiles = append(pngFiles, filepath.Join(root, file.Name()))
-			}
-		}
-	}
-
-	return pngFiles, nil
+// filter に一致しないファイルは除外し、除外されたディレクトリの中は探索しない
+// インデックスを使用せず、常にすべてのフォルダの中身を読み込む
+func listPNGFiles(root string, isRecursive bool, filter pathFilter) ([]string, error) {
+	return newLibraryIndex("").listPNGFiles(root, isRecursive, filter)
 }
 
 // PNGファイルリストからラダムに1つ選択する関数
 func pickRandomFile(files []string) (string, error) {
 	if len(files) == 0 {
-		return "", fmt.Errorf("no PNG files found")
+		return "", errNoPNGFiles
 	}
 
 	rand.Seed(uint64(time.Now().UnixNano())) // 現在時刻をシードにして乱数を初期化
@@ -65,28 +35,29 @@ func pickRandomFile(files []string) (string, error) {
 	return files[randomIndex], nil
 }
 
-// 画像を指定されたアスペクト比に切り取る関数
-func cropToAspectRatio(img image.Image, width, height int) image.Image {
-	srcBounds := img.Bounds()
-	srcWidth := srcBounds.Dx()
-	srcHeight := srcBounds.Dy()
-
+// 幅 srcWidth・高さ srcHeight の画像を、width:height のアスペクト比になるよう中央を基準に切り取る範囲を返す関数
+func aspectCropRect(srcWidth, srcHeight, width, height int) image.Rectangle {
 	srcAspectRatio := float64(srcWidth) / float64(srcHeight)
 	destAspectRatio := float64(width) / float64(height)
 
-	var cropRect image.Rectangle
 	if srcAspectRatio > destAspectRatio {
 		// 横長の場合、左右を切り取る
 		newWidth := int(destAspectRatio * float64(srcHeight))
 		x0 := (srcWidth - newWidth) / 2
-		cropRect = image.Rect(x0, 0, x0+newWidth, srcHeight)
-	} else {
-		// 縦長の場合、上下を切り取る
-		newHeight := int(float64(srcWidth) / destAspectRatio)
-		y0 := (srcHeight - newHeight) / 2
-		cropRect = image.Rect(0, y0, srcWidth, y0+newHeight)
+		return image.Rect(x0, 0, x0+newWidth, srcHeight)
 	}
 
+	// 縦長の場合、上下を切り取る
+	newHeight := int(float64(srcWidth) / destAspectRatio)
+	y0 := (srcHeight - newHeight) / 2
+	return image.Rect(0, y0, srcWidth, y0+newHeight)
+}
+
+// 画像を指定されたアスペクト比に切り取る関数
+func cropToAspectRatio(img image.Image, width, height int) image.Image {
+	srcBounds := img.Bounds()
+	cropRect := aspectCropRect(srcBounds.Dx(), srcBounds.Dy(), width, height).Add(srcBounds.Min)
+
 	// 指定された範囲を切り取る
 	croppedImg := img.(interface {
 		SubImage(r image.Rectangle) image.Image
@@ -99,42 +70,83 @@ func cropToAspectRatio(img image.Image, width, height int) image.Image {
 	return dst
 }
 
+// errImageTooLarge は、画像が大きすぎて読み込めない場合のエラーです。
+// この画像を選択した場合は、その画像を除外して選択し直します。
+var errImageTooLarge = errors.New("image is too large")
+
+// この画素数を超える PNG 画像は、全体をメモリに展開せずに縮小しながら読み込む
+// インターレースされた PNG は縮小しながら読み込めないため、この画素数を超える場合は読み込まない
+const largeImagePixels = 4096 * 4096
+
 // resizePNGFileは、指定されたPNG画像を指定の幅と高さにリサイズし、保存します。
 // リサイズの際、元の画像のアスペクト比が異なる場合は、中央を基準にクロップ（切り取り）します。
+// デコード前に画像の大きさを確認し、maxPixels を超える画像は errImageTooLarge とします（0 の場合は制限しません）。
+// largeImagePixels を超える PNG 画像は、メモリ使用量を抑えるため行単位で縮小しながら読み込みます。
+// ただし、largeImagePixels を超えるインターレースされた PNG 画像は、画像全体を展開する必要があるため errImageTooLarge とします。
 // - srcPath: 元のPNGファイルのパス
 // - destPath: リサイズ後のPNGファイルの保存先パス
 // - width: リサイズ後の画像の幅
 // - height: リサイズ後の画像の高さ
-func resizePNGFile(srcPath, destPath string, width, height int) error {
+// - maxPixels: 元の画像として許容する最大の画素数
+// - texts: 保存する画像に書き込むテキストチャンク
+func resizePNGFile(srcPath, destPath string, width, height, maxPixels int, texts ...pngText) error {
 	srcFile, err := os.Open(srcPath)
 	if err != nil {
 		return err
 	}
 	defer srcFile.Close()
 
-	srcImage, _, err := image.Decode(srcFile)
+	// 画像全体をデコードする前に、大きさだけを確認する
+	srcConfig, format, err := image.DecodeConfig(srcFile)
 	if err != nil {
 		return err
 	}
+	pixels := int64(srcConfig.Width) * int64(srcConfig.Height)
+	if maxPixels > 0 && pixels > int64(maxPixels) {
+		return fmt.Errorf("%w (%s: %dx%d, %d pixels exceeds the limit of %d pixels)", errImageTooLarge, srcPath, srcConfig.Width, srcConfig.Height, pixels, maxPixels)
+	}
+	if _, err := srcFile.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
 
-	// アスペクト比を調整
-	srcImage = cropToAspectRatio(srcImage, width, height)
-
-	destImage := image.NewRGBA(image.Rect(0, 0, width, height))
-	draw.CatmullRom.Scale(destImage, destImage.Rect, srcImage, srcImage.Bounds(), draw.Over, nil)
+	var srcImage image.Image
+	if format == "png" && pixels > largeImagePixels {
+		slog.Warn("Image is large, decoding with reduced memory usage", "path", srcPath, "width", srcConfig.Width, "height", srcConfig.Height)
 
-	destFile, err := os.Create(destPath)
+		// 切り取る範囲だけを、出力サイズまで縮小しながら読み込む
+		cropRect := aspectCropRect(srcConfig.Width, srcConfig.Height, width, height)
+		srcImage, err = decodePNGScaled(srcFile, cropRect, width, height)
+		if errors.Is(err, errPNGInterlaced) {
+			// 通常のデコードに切り替えると画像全体をメモリに展開するため、読み込まない
+			return fmt.Errorf("%w to decode as an interlaced PNG (%s: %dx%d, %d pixels exceeds the limit of %d pixels for interlaced images)", errImageTooLarge, srcPath, srcConfig.Width, srcConfig.Height, pixels, largeImagePixels)
+		}
+	} else {
+		srcImage, _, err = image.Decode(srcFile)
+	}
 	if err != nil {
 		return err
 	}
-	defer destFile.Close()
 
-	err = png.Encode(destFile, destImage)
-	if err != nil {
+	// アスペクト比を調整し、指定のサイズにリサイズする（縮小しながら読み込んだ場合は既に指定のサイズになっている）
+	destImage := srcImage
+	if bounds := srcImage.Bounds(); bounds.Dx() != width || bounds.Dy() != height {
+		destImage = cropToAspectRatio(srcImage, width, height)
+	}
+
+	var encoded bytes.Buffer
+	if err := png.Encode(&encoded, destImage); err != nil {
 		return err
 	}
 
-	return nil
+	// png.Encode はテキストチャンクを書き込まないため、エンコード後に挿入する
+	data := encoded.Bytes()
+	if len(texts) > 0 {
+		if data, err = insertPNGText(data, texts); err != nil {
+			return err
+		}
+	}
+
+	return os.WriteFile(destPath, data, 0644)
 }
 
 func isGoRun() bool {
@@ -151,131 +163,85 @@ func isGoRun() bool {
 	return strings.HasPrefix(executable, os.TempDir())
 }
 
-func getConfigPath(configParamPath *string) string {
-	// 設定ファイルパスは環境変数 CONFIG_PATH または引数 -config で指定し、指定されていない場合は "data/config.yml" とする。
-	// "data/config.yml" の場所は、実行ファイルと同じディレクトリにあるものとする。go runで実行する場合は、カレントディレクトリにあるものとする。
-	if *configParamPath != "" {
-		return *configParamPath
-	}
-
+// アプリケーションのデータファイルのパスを返す関数
+// データファイルは、実行ファイルと同じディレクトリの "data" フォルダに置くものとする。go runで実行する場合は、カレントディレクトリの "data" フォルダとする。
+func getDataFilePath(name string) string {
 	exePath, err := os.Executable()
 	if err != nil {
-		return filepath.Join("data", "config.yml")
+		return filepath.Join("data", name)
 	}
 
 	if isGoRun() {
-		return filepath.Join("data", "config.yml")
+		return filepath.Join("data", name)
 	}
 
 	exeDir := filepath.Dir(exePath)
-	return filepath.Join(exeDir, "data", "config.yml")
+	return filepath.Join(exeDir, "data", name)
 }
 
-func main() {
-	// コマンドライン引数を解析する
-	helpFlag := flag.Bool("help", false, "Show help message")
-	versionFlag := flag.Bool("version", false, "Show version")
-	configParamPath := flag.String("config", os.Getenv("CONFIG_PATH"), "Path to the configuration file")
-	flag.Parse()
-
-	// ヘルプメッセージを表示する
-	if *helpFlag {
-		printHelp()
-		return
-	}
-
-	// バージョン情報を表示する
-	if *versionFlag {
-		log.Println("splashscreen-changer")
-		log.Println("|- Version", GetAppVersion())
-		log.Println("|- Build date:", GetAppDate())
-		return
+func getConfigPath(configParamPath *string) string {
+	// 設定ファイルパスは環境変数 CONFIG_PATH または引数 -config で指定し、指定されていない場合は "data/config.yml" とする。
+	// "data/config.yml" の場所は、実行ファイルと同じディレクトリにあるものとする。go runで実行する場合は、カレントディレクトリにあるものとする。
+	if *configParamPath != "" {
+		return *configParamPath
 	}
 
-	// 設定ファイルを読み込む。
-	configPath := getConfigPath(configParamPath)
+	return getDataFilePath("config.yml")
+}
 
-	log.Println("Loading config file:", configPath)
-	config, err := LoadConfig(configPath)
+// pickFromLibrary は、設定された絞り込み条件でソース画像を絞り込み、ランダムに 1 つの画像を選択します。
+func pickFromLibrary(config *Config, candidates []candidate, sources []sourceSpec, index *libraryIndex) (string, error) {
+	candidates, err := filterCandidates(config, candidates, sources, index)
 	if err != nil {
-		log.Println("Failed to load configuration file:", err)
-		return
+		return "", err
 	}
 
-	// ログファイルを開く
-	path := getLogFilePath(&config.Log.Path)
-	// ログファイルの親ディレクトリが存在しない場合は作成する
-	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
-		log.Fatal(err)
+	// 似た画像をまとめて、1 つの画像として扱う
+	if config.Selection.Deduplicate {
+		total := len(candidates)
+		candidates = collapseDuplicates(groupDuplicates(candidates, index, config.Selection.DuplicateThreshold))
+		slog.Info("Grouped near-duplicate images", "files", total, "distinct", len(candidates))
 	}
-	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_RDWR, 0644)
-	if err != nil {
-		log.Fatal(err)
-	}
-
-	defer file.Close()
-	mw := io.MultiWriter(os.Stdout, file)
-	log.SetOutput(mw)
 
-	sourcePath, err := getSourcePath(config)
-	if err != nil {
-		log.Println("Failed to obtain source path")
-		log.Println()
-		log.Println("The following steps are used to obtain the source paths. This error occurs because the following steps could not be taken to obtain the source path.")
-		log.Println("1. Environment variable SOURCE_PATH. If this is not set, the following steps are taken.")
-		log.Println("2. source.path in Configuration file. If this is not set, the following steps are taken.")
-		log.Println("3. Check if the VRChat folder exists in the Pictures folder in the user folder.")
-		log.Println("If the VRChat folder exists, the path to the VRChat folder is used as the source path.")
-		return
-	}
+	// ランダムで1つのファイルを選択する
+	return pickCandidate(candidates, sources)
+}
 
-	destinationPath, err := getDestinationPath(config)
-	if err != nil {
-		log.Println("Failed to obtain destination path")
-		log.Println()
-		log.Println("The following steps are used to obtain the destination paths. This error occurs because the following steps could not be taken to obtain the destination path.")
-		log.Println("1. Environment variable DESTINATION_PATH. If this is not set, the following steps are taken.")
-		log.Println("2. destination.path in Configuration file. If this is not set, the following steps are taken.")
-		log.Println("3. Get the installation destination folder of VRChat from the Steam library folder.")
-		log.Println("If the EasyAntiCheat folder exists in the VRChat folder, the path to the VRChat folder is used as the destination path.")
-		return
+// filterCandidates は、設定された絞り込み条件（画像サイズ・タグ・スクリーンショットのメタデータ）を満たすソース画像のみを返します。
+func filterCandidates(config *Config, candidates []candidate, sources []sourceSpec, index *libraryIndex) ([]candidate, error) {
+	// 画像サイズでソース画像を絞り込む
+	filter := newDimensionFilter(config)
+	if !filter.isEmpty() {
+		total := len(candidates)
+		candidates = filterByDimensions(candidates, filter, index)
+		slog.Info("Applied dimension filters", "matched", len(candidates), "total", total)
 	}
 
-	// 設定値を表示する
-	log.Printf("Source Path: %s\n", sourcePath)
-	log.Printf("Source Recursive: %t\n", config.Source.Recursive)
-	log.Printf("Destination Path: %s\n", destinationPath)
-	log.Printf("Destination Width: %d\n", config.Destination.Width)
-	log.Printf("Destination Height: %d\n", config.Destination.Height)
-
-	// ソースディレクトリ以下のPNGファイルをリストする
-	files, err := listPNGFiles(sourcePath, config.Source.Recursive)
+	// タグでソース画像を絞り込む
+	tagFilter, err := newTagFilter(config)
 	if err != nil {
-		log.Println("Error:", err)
-		return
+		return nil, withExitCode(exitConfigError, err)
 	}
-
-	// ランダムで1つのファイルを選択する
-	if len(files) == 0 {
-		log.Println("No PNG files found")
-		return
+	if !tagFilter.isEmpty() {
+		total := len(candidates)
+		candidates = filterByTags(candidates, sources, tagFilter, index)
+		slog.Info("Applied tag filters", "matched", len(candidates), "total", total)
 	}
 
-	pickedFile, err := pickRandomFile(files)
-	if err != nil {
-		log.Println("Error:", err)
-		return
+	// スクリーンショットのワールド・プレイヤー・撮影日時でソース画像を絞り込む
+	screenshotFilter := newScreenshotFilter(config, time.Now())
+	if !screenshotFilter.isEmpty() {
+		total := len(candidates)
+		candidates = filterByScreenshot(candidates, screenshotFilter, index)
+		slog.Info("Applied screenshot filters", "matched", len(candidates), "total", total)
 	}
+	return candidates, nil
+}
 
-	log.Println("Picked file:", pickedFile)
-
-	// ファイルをリサイズして EasyAntiCheat ディレクトリに保存する
-	destFile := filepath.Join(destinationPath, "EasyAntiCheat", "SplashScreen.png")
-	err = resizePNGFile(pickedFile, destFile, config.Destination.Width, config.Destination.Height)
+func main() {
+	err := runCLI(os.Args[1:], os.Stdout)
 	if err != nil {
-		log.Println("Error:", err)
-		return
+		slog.Error("Command failed", "error", err, "exit_code", exitCodeOf(err))
 	}
-
-	log.Println("Resized file saved to:", destFile)
+	os.Exit(exitCodeOf(err))
 }
@@ -1,10 +1,13 @@
 package main
 
 import (
+	"fmt"
 	"image"
 	"image/png"
 	"os"
 	"path/filepath"
+	"runtime"
+	"strings"
 	"testing"
 )
 
@@ -26,7 +29,7 @@ func TestListPNGFiles(t *testing.T) {
 	file3.Close()
 
 	// Test non-recursive listing
-	files, err := listPNGFiles(tmpDir, false)
+	files, err := listPNGFiles(tmpDir, false, pathFilter{})
 	if err != nil {
 		t.Fatalf("Expected no error, got %v", err)
 	}
@@ -40,7 +43,7 @@ func TestListPNGFiles(t *testing.T) {
 	file4, _ := os.Create(filepath.Join(subDir, "test4.png"))
 	file4.Close()
 
-	files, err = listPNGFiles(tmpDir, true)
+	files, err = listPNGFiles(tmpDir, true, pathFilter{})
 	if err != nil {
 		t.Fatalf("Expected no error, got %v", err)
 	}
@@ -49,6 +52,54 @@ func TestListPNGFiles(t *testing.T) {
 	}
 }
 
+// Test listPNGFiles function with include/exclude patterns
+func TestListPNGFilesWithFilter(t *testing.T) {
+	tmpDir := t.TempDir()
+	for _, name := range []string{
+		"a.png",
+		".hidden.png",
+		"2026-01/b.png",
+		"2026-01/thumbnails/b_thumb.png",
+		"2026-01/edits/c_edit.png",
+		"private/d.png",
+		"private/nested/e.png",
+		".hidden/f.png",
+	} {
+		path := filepath.Join(tmpDir, filepath.FromSlash(name))
+		os.MkdirAll(filepath.Dir(path), os.ModePerm)
+		f, _ := os.Create(path)
+		f.Close()
+	}
+
+	tests := []struct {
+		name      string
+		recursive bool
+		filter    pathFilter
+		want      int
+	}{
+		{"No filter", true, pathFilter{}, 8},
+		{"Exclude folders anywhere", true, pathFilter{Exclude: []string{"thumbnails", "edits"}}, 6},
+		{"Exclude nested folder", true, pathFilter{Exclude: []string{"private/**"}}, 6},
+		{"Include only a folder", true, pathFilter{Include: []string{"2026-*/**"}}, 3},
+		{"Include and exclude", true, pathFilter{Include: []string{"2026-*/**"}, Exclude: []string{"**/thumbnails/**"}}, 2},
+		{"Skip hidden", true, pathFilter{SkipHidden: true}, 6},
+		{"Non-recursive with exclude", false, pathFilter{Exclude: []string{"a.png"}}, 1},
+		{"Non-recursive skip hidden", false, pathFilter{SkipHidden: true}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files, err := listPNGFiles(tmpDir, tt.recursive, tt.filter)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if len(files) != tt.want {
+				t.Fatalf("Expected %d PNG files, got %d: %v", tt.want, len(files), files)
+			}
+		})
+	}
+}
+
 // Test pickRandomFile function
 func TestPickRandomFile(t *testing.T) {
 	files := []string{"file1.png", "file2.png", "file3.png"}
@@ -92,7 +143,7 @@ func TestResizePNGFile(t *testing.T) {
 	png.Encode(f, img)
 	f.Close()
 
-	err := resizePNGFile(srcPath, destPath, 50, 50)
+	err := resizePNGFile(srcPath, destPath, 50, 50, 0)
 	if err != nil {
 		t.Fatalf("Expected no error, got %v", err)
 	}
@@ -110,3 +161,103 @@ func TestResizePNGFile(t *testing.T) {
 		t.Fatalf("Expected resized image to be 50x50, got %dx%d", destImg.Bounds().Dx(), destImg.Bounds().Dy())
 	}
 }
+
+// Test that resizePNGFile rejects images over the pixel limit before decoding
+func TestResizePNGFileMaxPixels(t *testing.T) {
+	tempDir := t.TempDir()
+	srcPath := filepath.Join(tempDir, "src.png")
+	destPath := filepath.Join(tempDir, "dest.png")
+
+	img := image.NewRGBA(image.Rect(0, 0, 100, 200))
+	f, _ := os.Create(srcPath)
+	png.Encode(f, img)
+	f.Close()
+
+	err := resizePNGFile(srcPath, destPath, 50, 50, 100*200-1)
+	if err == nil {
+		t.Fatalf("Expected an error, got nil")
+	}
+	if _, err := os.Stat(destPath); !os.IsNotExist(err) {
+		t.Fatalf("Expected destination file not to be created")
+	}
+
+	if err := resizePNGFile(srcPath, destPath, 50, 50, 100*200); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+}
+
+// Test that resizePNGFile skips a huge interlaced PNG instead of decoding it entirely
+func TestResizePNGFileLargeInterlaced(t *testing.T) {
+	tempDir := t.TempDir()
+	srcPath := filepath.Join(tempDir, "interlaced.png")
+	destPath := filepath.Join(tempDir, "dest.png")
+	writeInterlacedTestPNG(t, srcPath, 5000, 5000)
+
+	err := resizePNGFile(srcPath, destPath, 800, 450, 0)
+	if err == nil || !strings.Contains(err.Error(), "interlaced") {
+		t.Fatalf("Expected an error for the interlaced image, got %v", err)
+	}
+	if _, err := os.Stat(destPath); !os.IsNotExist(err) {
+		t.Fatalf("Expected destination file not to be created")
+	}
+}
+
+// Test that resizing a huge PNG keeps memory usage bounded
+func TestResizePNGFileLargeImage(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping large image test in short mode")
+	}
+
+	tempDir := t.TempDir()
+	srcPath := filepath.Join(tempDir, "large.png")
+	destPath := filepath.Join(tempDir, "dest.png")
+	width, height := 7680, 4320
+	writeLargeTestPNG(t, srcPath, width, height)
+
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+
+	if err := resizePNGFile(srcPath, destPath, 800, 450, 0); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	runtime.ReadMemStats(&after)
+
+	// Decoding the whole image would allocate at least width*height*4 bytes
+	fullDecode := uint64(width * height * 4)
+	allocated := after.TotalAlloc - before.TotalAlloc
+	if allocated > fullDecode/4 {
+		t.Fatalf("Expected less than %d bytes to be allocated, got %d", fullDecode/4, allocated)
+	}
+
+	destFile, _ := os.Open(destPath)
+	defer destFile.Close()
+	destImg, _, err := image.Decode(destFile)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if destImg.Bounds().Dx() != 800 || destImg.Bounds().Dy() != 450 {
+		t.Fatalf("Expected resized image to be 800x450, got %dx%d", destImg.Bounds().Dx(), destImg.Bounds().Dy())
+	}
+}
+
+// Benchmark resizePNGFile function with a source image larger than largeImagePixels
+func BenchmarkResizePNGFile(b *testing.B) {
+	for _, size := range []image.Point{{1920, 1080}, {7680, 4320}} {
+		b.Run(fmt.Sprintf("%dx%d", size.X, size.Y), func(b *testing.B) {
+			tempDir := b.TempDir()
+			srcPath := filepath.Join(tempDir, "src.png")
+			destPath := filepath.Join(tempDir, "dest.png")
+			writeLargeTestPNG(b, srcPath, size.X, size.Y)
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if err := resizePNGFile(srcPath, destPath, 800, 450, 0); err != nil {
+					b.Fatalf("Expected no error, got %v", err)
+				}
+			}
+		})
+	}
+}
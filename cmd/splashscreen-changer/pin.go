@@ -0,0 +1,156 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// pinnedImage は、pin コマンドで固定した画像です。
+type pinnedImage struct {
+	Path string `json:"path"`
+	// Until は、固定を解除する日時。ゼロ値の場合は unpin コマンドで解除するまで固定する
+	Until time.Time `json:"until,omitzero"`
+}
+
+// pin コマンドの -until 引数で受け付ける日時の書式（タイムゾーンの指定がない場合はローカル時刻とする）
+var untilLayouts = []string{
+	"2006-01-02T15:04",
+	"2006-01-02 15:04",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+}
+
+// parseUntil は、-until 引数の値を解析します。以下の形式を受け付けます。
+// - RFC 3339 形式の日時（例: 2026-10-20T21:00:00+09:00）
+// - タイムゾーンのない日時（例: 2026-10-20 21:00）。ローカル時刻として扱う
+// - 日付のみ（例: 2026-10-20）。その日の終わりまでとする
+// - 現在からの期間（例: 36h）
+func parseUntil(value string, now time.Time) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	for _, layout := range untilLayouts {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	if t, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		return t.AddDate(0, 0, 1), nil
+	}
+	if d, err := time.ParseDuration(value); err == nil && d > 0 {
+		return now.Add(d), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid time '%s' (expected a date such as 2026-10-20, a time such as 2026-10-20 21:00 or a duration such as 36h)", value)
+}
+
+// resolveForcedImage は、pick・pin コマンドで指定された画像のパスを確認し、絶対パスを返します。
+func resolveForcedImage(path string) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", fmt.Errorf("image '%s' does not exist", path)
+	}
+	if info.IsDir() || !strings.HasSuffix(strings.ToLower(path), ".png") {
+		return "", fmt.Errorf("image '%s' is not a PNG file", path)
+	}
+	return filepath.Abs(path)
+}
+
+// setNextPick は、次回の実行で 1 回だけ使用する画像を設定します。
+func (state *appState) setNextPick(path string) error {
+	abs, err := resolveForcedImage(path)
+	if err != nil {
+		return err
+	}
+	state.NextPick = abs
+	return nil
+}
+
+// setPin は、期限まで使い続ける画像を設定します。until がゼロ値の場合は期限を設けません。
+func (state *appState) setPin(path string, until, now time.Time) error {
+	abs, err := resolveForcedImage(path)
+	if err != nil {
+		return err
+	}
+	if !until.IsZero() && !until.After(now) {
+		return fmt.Errorf("pin expiry %s is in the past", until.Format(time.RFC3339))
+	}
+	state.Pin = &pinnedImage{Path: abs, Until: until}
+	return nil
+}
+
+// forcedPick は、ランダムな選択の代わりに使用する画像を返します。
+// pick コマンドで設定された画像を pin コマンドで固定された画像より優先し、pick の画像は一度使用すると削除します。
+// 期限切れの固定や、存在しなくなった画像は解除します。使用する画像がない場合は false を返します。
+func (state *appState) forcedPick(now time.Time) (string, bool) {
+	if path := state.NextPick; path != "" {
+		state.NextPick = ""
+		if _, err := os.Stat(path); err == nil {
+			slog.Info("Using the image set by pick", "path", path)
+			return path, true
+		}
+		slog.Warn("Image set by pick no longer exists, picking randomly", "path", path)
+	}
+
+	if state.Pin == nil {
+		return "", false
+	}
+	pin := *state.Pin
+	if !pin.Until.IsZero() && !now.Before(pin.Until) {
+		slog.Info("Pin expired", "path", pin.Path, "until", pin.Until)
+		state.Pin = nil
+		return "", false
+	}
+	if _, err := os.Stat(pin.Path); err != nil {
+		slog.Warn("Pinned image no longer exists, unpinning", "path", pin.Path)
+		state.Pin = nil
+		return "", false
+	}
+	slog.Info("Using the pinned image", "path", pin.Path)
+	return pin.Path, true
+}
+
+// updateForcedImage は、pick・pin・unpin コマンドの引数に従って状態ファイルを更新します。
+func updateForcedImage(pick, pin, until string, unpin bool) error {
+	if until != "" && pin == "" {
+		return fmt.Errorf("-until can only be used with pin (pin <image path> -until <expiry>)")
+	}
+
+	state, err := loadState(getDataFilePath("state.json"))
+	if err != nil {
+		return fmt.Errorf("failed to load state file: %w", err)
+	}
+
+	now := time.Now()
+	if unpin {
+		state.Pin = nil
+		slog.Info("Unpinned the image")
+	}
+	if pin != "" {
+		var expiry time.Time
+		if until != "" {
+			if expiry, err = parseUntil(until, now); err != nil {
+				return err
+			}
+		}
+		if err := state.setPin(pin, expiry, now); err != nil {
+			return err
+		}
+		if expiry.IsZero() {
+			slog.Info("Pinned image", "path", state.Pin.Path)
+		} else {
+			slog.Info("Pinned image", "path", state.Pin.Path, "until", expiry)
+		}
+	}
+	if pick != "" {
+		if err := state.setNextPick(pick); err != nil {
+			return err
+		}
+		slog.Info("The next run will use the picked image", "path", state.NextPick)
+	}
+	return state.save()
+}
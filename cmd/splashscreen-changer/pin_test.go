@@ -0,0 +1,121 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParseUntil(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.Local)
+	tests := []struct {
+		value    string
+		expected time.Time
+	}{
+		{"2026-10-20T21:00:00+09:00", time.Date(2026, 10, 20, 12, 0, 0, 0, time.UTC)},
+		{"2026-10-20 21:00", time.Date(2026, 10, 20, 21, 0, 0, 0, time.Local)},
+		{"2026-10-20T21:00:30", time.Date(2026, 10, 20, 21, 0, 30, 0, time.Local)},
+		{"2026-10-20", time.Date(2026, 10, 21, 0, 0, 0, 0, time.Local)},
+		{"36h", now.Add(36 * time.Hour)},
+	}
+	for _, tt := range tests {
+		got, err := parseUntil(tt.value, now)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.value, err)
+			continue
+		}
+		if !got.Equal(tt.expected) {
+			t.Errorf("%s: expected %v, got %v", tt.value, tt.expected, got)
+		}
+	}
+
+	for _, value := range []string{"", "tomorrow", "-1h", "2026-13-01"} {
+		if _, err := parseUntil(value, now); err == nil {
+			t.Errorf("%s: expected an error", value)
+		}
+	}
+}
+
+func TestSetPinAndNextPick(t *testing.T) {
+	dir := t.TempDir()
+	image := filepath.Join(dir, "event.png")
+	writeTestPNG(t, image, 16, 9)
+	now := time.Now()
+
+	state := &appState{}
+	if err := state.setPin(filepath.Join(dir, "missing.png"), time.Time{}, now); err == nil {
+		t.Error("Expected an error for a missing image")
+	}
+	if err := state.setPin(dir, time.Time{}, now); err == nil {
+		t.Error("Expected an error for a directory")
+	}
+	if err := state.setPin(image, now.Add(-time.Hour), now); err == nil {
+		t.Error("Expected an error for an expiry in the past")
+	}
+	if err := state.setPin(image, now.Add(time.Hour), now); err != nil {
+		t.Fatalf("setPin failed: %v", err)
+	}
+	abs, _ := filepath.Abs(image)
+	if state.Pin == nil || state.Pin.Path != abs {
+		t.Errorf("Expected %s to be pinned, got %+v", abs, state.Pin)
+	}
+
+	if err := state.setNextPick(filepath.Join(dir, "missing.png")); err == nil {
+		t.Error("Expected an error for a missing image")
+	}
+	if err := state.setNextPick(image); err != nil {
+		t.Fatalf("setNextPick failed: %v", err)
+	}
+	if state.NextPick != abs {
+		t.Errorf("Expected next pick %s, got %s", abs, state.NextPick)
+	}
+}
+
+func TestForcedPick(t *testing.T) {
+	dir := t.TempDir()
+	pinned := filepath.Join(dir, "pinned.png")
+	next := filepath.Join(dir, "next.png")
+	writeTestPNG(t, pinned, 16, 9)
+	writeTestPNG(t, next, 16, 9)
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+
+	state := &appState{
+		NextPick: next,
+		Pin:      &pinnedImage{Path: pinned, Until: now.Add(time.Hour)},
+	}
+
+	// -pick takes precedence over -pin and is used only once
+	if path, ok := state.forcedPick(now); !ok || path != next {
+		t.Errorf("Expected the next pick, got %s (%v)", path, ok)
+	}
+	if state.NextPick != "" {
+		t.Errorf("Expected the next pick to be cleared, got %s", state.NextPick)
+	}
+
+	// The pin is kept until it expires
+	for i := 0; i < 2; i++ {
+		if path, ok := state.forcedPick(now); !ok || path != pinned {
+			t.Errorf("Expected the pinned image, got %s (%v)", path, ok)
+		}
+	}
+	if _, ok := state.forcedPick(now.Add(time.Hour)); ok {
+		t.Error("Expected no forced image after the pin expired")
+	}
+	if state.Pin != nil {
+		t.Errorf("Expected the expired pin to be removed, got %+v", state.Pin)
+	}
+
+	// A pin without expiry stays until the image disappears
+	state.Pin = &pinnedImage{Path: pinned}
+	if path, ok := state.forcedPick(now.AddDate(10, 0, 0)); !ok || path != pinned {
+		t.Errorf("Expected the pinned image, got %s (%v)", path, ok)
+	}
+	state.Pin = &pinnedImage{Path: filepath.Join(dir, "deleted.png")}
+	state.NextPick = filepath.Join(dir, "deleted.png")
+	if _, ok := state.forcedPick(now); ok {
+		t.Error("Expected no forced image when the images do not exist")
+	}
+	if state.Pin != nil || state.NextPick != "" {
+		t.Errorf("Expected missing images to be cleared, got %+v / %s", state.Pin, state.NextPick)
+	}
+}
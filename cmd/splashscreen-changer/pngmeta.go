@@ -0,0 +1,237 @@
+package main
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+)
+
+// テキストチャンクとして読み込むデータの最大サイズ（展開後）
+const maxPNGTextLength = 1 << 20
+
+// pngText は、PNG のテキストチャンク（tEXt / zTXt / iTXt）の内容です。
+type pngText struct {
+	Keyword string
+	Text    string
+}
+
+// readPNGTextFile は、指定されたファイルの IHDR チャンクとテキストチャンクを読み込みます。
+func readPNGTextFile(path string) (pngHeader, []pngText, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return pngHeader{}, nil, err
+	}
+	defer f.Close()
+
+	return readPNGText(f)
+}
+
+// readPNGText は、PNG の IHDR チャンクとテキストチャンクを読み込みます。
+// テキストチャンクは画像データの後ろに置かれることもあるため、IEND チャンクまで読み込みます。
+// 画像データ（IDAT チャンク）は読み込まずに読み飛ばします。
+func readPNGText(r io.Reader) (pngHeader, []pngText, error) {
+	c, err := newPNGChunkReader(r)
+	if err != nil {
+		return pngHeader{}, nil, err
+	}
+
+	var header pngHeader
+	var texts []pngText
+	for {
+		length, chunkType, err := c.next()
+		if err != nil {
+			return pngHeader{}, nil, err
+		}
+
+		switch chunkType {
+		case "IHDR":
+			data, err := c.readData(chunkType, length)
+			if err != nil {
+				return pngHeader{}, nil, err
+			}
+			if header, err = parsePNGHeader(data); err != nil {
+				return pngHeader{}, nil, err
+			}
+		case "tEXt", "zTXt", "iTXt":
+			data, err := c.readData(chunkType, length)
+			if err != nil {
+				return pngHeader{}, nil, err
+			}
+			text, err := parsePNGText(chunkType, data)
+			if err != nil {
+				return pngHeader{}, nil, err
+			}
+			texts = append(texts, text)
+		case "IEND":
+			if header.Width == 0 {
+				return pngHeader{}, nil, fmt.Errorf("missing IHDR chunk")
+			}
+			return header, texts, nil
+		default:
+			if err := c.skip(length); err != nil {
+				return pngHeader{}, nil, err
+			}
+		}
+	}
+}
+
+// skip は、チャンクのデータと CRC を読み飛ばします。読み飛ばしたデータの CRC は検証しません。
+func (c *pngChunkReader) skip(length uint32) error {
+	n := int64(length) + 4
+	if seeker, ok := c.r.(io.Seeker); ok {
+		_, err := seeker.Seek(n, io.SeekCurrent)
+		return err
+	}
+	_, err := io.CopyN(io.Discard, c.r, n)
+	return err
+}
+
+// parsePNGText は、テキストチャンクのデータを解析します。
+// - tEXt: キーワード、NUL、テキスト（Latin-1）
+// - zTXt: キーワード、NUL、圧縮方式、zlib で圧縮されたテキスト（Latin-1）
+// - iTXt: キーワード、NUL、圧縮フラグ、圧縮方式、言語タグ、NUL、翻訳されたキーワード、NUL、テキスト（UTF-8、圧縮されている場合あり）
+func parsePNGText(chunkType string, data []byte) (pngText, error) {
+	keyword, rest, ok := bytes.Cut(data, []byte{0})
+	if !ok || len(keyword) == 0 {
+		return pngText{}, fmt.Errorf("invalid %s chunk", chunkType)
+	}
+	text := pngText{Keyword: latin1ToString(keyword)}
+
+	switch chunkType {
+	case "tEXt":
+		text.Text = latin1ToString(rest)
+	case "zTXt":
+		if len(rest) < 1 || rest[0] != 0 {
+			return pngText{}, fmt.Errorf("invalid %s chunk", chunkType)
+		}
+		inflated, err := inflatePNGText(rest[1:])
+		if err != nil {
+			return pngText{}, fmt.Errorf("invalid %s chunk: %w", chunkType, err)
+		}
+		text.Text = latin1ToString(inflated)
+	case "iTXt":
+		if len(rest) < 2 {
+			return pngText{}, fmt.Errorf("invalid %s chunk", chunkType)
+		}
+		compressed := rest[0] != 0
+		// 言語タグと翻訳されたキーワードは使用しない
+		_, rest, ok = bytes.Cut(rest[2:], []byte{0})
+		if ok {
+			_, rest, ok = bytes.Cut(rest, []byte{0})
+		}
+		if !ok {
+			return pngText{}, fmt.Errorf("invalid %s chunk", chunkType)
+		}
+		if compressed {
+			inflated, err := inflatePNGText(rest)
+			if err != nil {
+				return pngText{}, fmt.Errorf("invalid %s chunk: %w", chunkType, err)
+			}
+			rest = inflated
+		}
+		text.Text = string(rest)
+	}
+	return text, nil
+}
+
+// inflatePNGText は、zlib で圧縮されたテキストを展開します。
+func inflatePNGText(data []byte) ([]byte, error) {
+	zr, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+
+	inflated, err := io.ReadAll(io.LimitReader(zr, maxPNGTextLength+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(inflated) > maxPNGTextLength {
+		return nil, fmt.Errorf("text is too large")
+	}
+	return inflated, nil
+}
+
+// latin1ToString は、Latin-1 の文字列を UTF-8 の文字列に変換します。
+func latin1ToString(data []byte) string {
+	runes := make([]rune, len(data))
+	for i, b := range data {
+		runes[i] = rune(b)
+	}
+	return string(runes)
+}
+
+// insertPNGText は、エンコード済みの PNG の IHDR チャンクの直後にテキストチャンクを挿入します。
+func insertPNGText(encoded []byte, texts []pngText) ([]byte, error) {
+	// シグネチャ (8 バイト) + IHDR チャンク (長さ・種類・データ 13 バイト・CRC)
+	ihdrEnd := len(pngSignature) + 8 + 13 + 4
+	if len(encoded) < ihdrEnd || string(encoded[:len(pngSignature)]) != pngSignature || string(encoded[len(pngSignature)+4:len(pngSignature)+8]) != "IHDR" {
+		return nil, fmt.Errorf("not a PNG file")
+	}
+
+	var buf bytes.Buffer
+	buf.Write(encoded[:ihdrEnd])
+	for _, text := range texts {
+		if err := writePNGText(&buf, text); err != nil {
+			return nil, err
+		}
+	}
+	buf.Write(encoded[ihdrEnd:])
+	return buf.Bytes(), nil
+}
+
+// writePNGText は、テキストを PNG のテキストチャンクとして書き込みます。
+// Latin-1 で表せるテキストは tEXt チャンク、それ以外のテキストと XMP は iTXt チャンクとして書き込みます。
+func writePNGText(w io.Writer, text pngText) error {
+	keyword, ok := stringToLatin1(text.Keyword)
+	if !ok || len(keyword) == 0 || len(keyword) > 79 || bytes.IndexByte(keyword, 0) >= 0 {
+		return fmt.Errorf("invalid PNG text keyword '%s'", text.Keyword)
+	}
+
+	var data []byte
+	chunkType := "tEXt"
+	if latin1, ok := stringToLatin1(text.Text); ok && text.Keyword != "XML:com.adobe.xmp" {
+		data = append(append(keyword, 0), latin1...)
+	} else {
+		// 圧縮なし、言語タグ・翻訳されたキーワードなし
+		chunkType = "iTXt"
+		data = append(append(keyword, 0, 0, 0, 0, 0), text.Text...)
+	}
+	return writePNGChunk(w, chunkType, data)
+}
+
+// writePNGChunk は、PNG のチャンクを CRC とともに書き込みます。
+func writePNGChunk(w io.Writer, chunkType string, data []byte) error {
+	var header [8]byte
+	binary.BigEndian.PutUint32(header[:4], uint32(len(data)))
+	copy(header[4:], chunkType)
+
+	crc := crc32.NewIEEE()
+	crc.Write(header[4:])
+	crc.Write(data)
+	var sum [4]byte
+	binary.BigEndian.PutUint32(sum[:], crc.Sum32())
+
+	for _, b := range [][]byte{header[:], data, sum[:]} {
+		if _, err := w.Write(b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// stringToLatin1 は、UTF-8 の文字列を Latin-1 に変換します。Latin-1 で表せない文字を含む場合は false を返します。
+func stringToLatin1(s string) ([]byte, bool) {
+	data := make([]byte, 0, len(s))
+	for _, r := range s {
+		if r > 0xff {
+			return nil, false
+		}
+		data = append(data, byte(r))
+	}
+	return data, true
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"testing"
+)
+
+// buildTestPNGWithChunks builds a 1x1 grayscale PNG with the given chunks before and after the image data.
+func buildTestPNGWithChunks(before, after func(*bytes.Buffer)) []byte {
+	var buf bytes.Buffer
+	buf.WriteString(pngSignature)
+	ihdr := make([]byte, 13)
+	binary.BigEndian.PutUint32(ihdr[0:4], 1)
+	binary.BigEndian.PutUint32(ihdr[4:8], 1)
+	ihdr[8] = 8
+	ihdr[9] = pngColorGray
+	writeTestPNGChunk(&buf, "IHDR", ihdr)
+	if before != nil {
+		before(&buf)
+	}
+
+	var idat bytes.Buffer
+	zw := zlib.NewWriter(&idat)
+	zw.Write([]byte{0, 0x80})
+	zw.Close()
+	writeTestPNGChunk(&buf, "IDAT", idat.Bytes())
+	if after != nil {
+		after(&buf)
+	}
+	writeTestPNGChunk(&buf, "IEND", nil)
+	return buf.Bytes()
+}
+
+func compressTestText(text string) []byte {
+	var buf bytes.Buffer
+	zw := zlib.NewWriter(&buf)
+	zw.Write([]byte(text))
+	zw.Close()
+	return buf.Bytes()
+}
+
+func TestReadPNGText(t *testing.T) {
+	data := buildTestPNGWithChunks(func(buf *bytes.Buffer) {
+		writeTestPNGChunk(buf, "tEXt", []byte("Title\x00caf\xe9"))
+		writeTestPNGChunk(buf, "zTXt", append([]byte("Comment\x00\x00"), compressTestText("compressed text")...))
+	}, func(buf *bytes.Buffer) {
+		writeTestPNGChunk(buf, "iTXt", []byte("Author\x00\x00\x00ja\x00著者\x00撮影者"))
+		writeTestPNGChunk(buf, "iTXt", append([]byte("Description\x00\x01\x00\x00\x00"), compressTestText("圧縮された説明")...))
+	})
+
+	header, texts, err := readPNGText(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("readPNGText failed: %v", err)
+	}
+	if header.Width != 1 || header.Height != 1 {
+		t.Errorf("Expected 1x1 header, got %dx%d", header.Width, header.Height)
+	}
+
+	expected := []pngText{
+		{Keyword: "Title", Text: "café"},
+		{Keyword: "Comment", Text: "compressed text"},
+		{Keyword: "Author", Text: "撮影者"},
+		{Keyword: "Description", Text: "圧縮された説明"},
+	}
+	if len(texts) != len(expected) {
+		t.Fatalf("Expected %d texts, got %d: %v", len(expected), len(texts), texts)
+	}
+	for i, text := range expected {
+		if texts[i] != text {
+			t.Errorf("Text %d: expected %v, got %v", i, text, texts[i])
+		}
+	}
+}
+
+func TestReadPNGTextWithoutSeeker(t *testing.T) {
+	data := buildTestPNGWithChunks(nil, func(buf *bytes.Buffer) {
+		writeTestPNGChunk(buf, "tEXt", []byte("Software\x00test"))
+	})
+
+	// bytes.Buffer does not implement io.Seeker, so chunks are skipped by reading them
+	_, texts, err := readPNGText(bytes.NewBuffer(data))
+	if err != nil {
+		t.Fatalf("readPNGText failed: %v", err)
+	}
+	if len(texts) != 1 || texts[0].Text != "test" {
+		t.Errorf("Expected one text 'test', got %v", texts)
+	}
+}
+
+func TestReadPNGTextInvalid(t *testing.T) {
+	tests := map[string][]byte{
+		"not a PNG": []byte("GIF89a"),
+		"truncated": buildTestPNGWithChunks(nil, nil)[:40],
+		"missing keyword": buildTestPNGWithChunks(func(buf *bytes.Buffer) {
+			writeTestPNGChunk(buf, "tEXt", []byte("no separator"))
+		}, nil),
+		"broken zTXt": buildTestPNGWithChunks(func(buf *bytes.Buffer) {
+			writeTestPNGChunk(buf, "zTXt", []byte("Comment\x00\x00not zlib"))
+		}, nil),
+	}
+
+	// A text chunk with a corrupted checksum
+	corrupted := buildTestPNGWithChunks(func(buf *bytes.Buffer) {
+		writeTestPNGChunk(buf, "tEXt", []byte("Title\x00text"))
+	}, nil)
+	corrupted[len(pngSignature)+8+13+4+8] ^= 0xff
+	tests["bad checksum"] = corrupted
+
+	for name, data := range tests {
+		if _, _, err := readPNGText(bytes.NewReader(data)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestInsertPNGText(t *testing.T) {
+	texts := []pngText{
+		{Keyword: "Software", Text: "café"},
+		{Keyword: "Source", Text: "C:\\Users\\ユーザー\\写真.png"},
+		{Keyword: "XML:com.adobe.xmp", Text: "<x:xmpmeta/>"},
+	}
+	data, err := insertPNGText(buildTestPNGWithChunks(nil, nil), texts)
+	if err != nil {
+		t.Fatalf("insertPNGText failed: %v", err)
+	}
+
+	_, read, err := readPNGText(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("readPNGText failed: %v", err)
+	}
+	if len(read) != len(texts) {
+		t.Fatalf("Expected %d texts, got %v", len(texts), read)
+	}
+	for i, text := range texts {
+		if read[i] != text {
+			t.Errorf("Text %d: expected %v, got %v", i, text, read[i])
+		}
+	}
+
+	// Latin-1 text is written as tEXt, other text and XMP as iTXt
+	for i, chunkType := range []string{"tEXt", "iTXt", "iTXt"} {
+		if !bytes.Contains(data, []byte(chunkType+texts[i].Keyword)) {
+			t.Errorf("Expected %s to be written as %s", texts[i].Keyword, chunkType)
+		}
+	}
+}
+
+func TestInsertPNGTextInvalid(t *testing.T) {
+	if _, err := insertPNGText([]byte("not a PNG"), nil); err == nil {
+		t.Error("Expected an error for invalid PNG data")
+	}
+	for _, keyword := range []string{"", "キーワード", string(make([]byte, 80))} {
+		if _, err := insertPNGText(buildTestPNGWithChunks(nil, nil), []pngText{{Keyword: keyword, Text: "text"}}); err == nil {
+			t.Errorf("Expected an error for keyword %q", keyword)
+		}
+	}
+}
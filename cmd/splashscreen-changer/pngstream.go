@@ -0,0 +1,484 @@
+package main
+
+import (
+	"bufio"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"io"
+)
+
+// PNG ファイルのシグネチャ
+const pngSignature = "\x89PNG\r\n\x1a\n"
+
+// 1 チャンクあたりに読み込みを許可する最大サイズ（IDAT を除く）
+const maxPNGChunkLength = 64 << 20
+
+// インターレースされた PNG はストリーミングでは縮小できないため、このエラーを返して通常のデコードに切り替える
+var errPNGInterlaced = errors.New("interlaced PNG is not supported by the streaming decoder")
+
+// PNG の IHDR チャンクの内容
+type pngHeader struct {
+	Width      int
+	Height     int
+	BitDepth   int
+	ColorType  int
+	Interlaced bool
+}
+
+// PNG のカラータイプ
+const (
+	pngColorGray      = 0
+	pngColorRGB       = 2
+	pngColorPalette   = 3
+	pngColorGrayAlpha = 4
+	pngColorRGBA      = 6
+)
+
+// pngChunkReader は、PNG のチャンクを先頭から順番に読み込みます。
+type pngChunkReader struct {
+	r   io.Reader
+	buf [8]byte
+}
+
+// newPNGChunkReader は、シグネチャを検証した上で pngChunkReader を作成します。
+func newPNGChunkReader(r io.Reader) (*pngChunkReader, error) {
+	sig := make([]byte, len(pngSignature))
+	if _, err := io.ReadFull(r, sig); err != nil {
+		return nil, err
+	}
+	if string(sig) != pngSignature {
+		return nil, fmt.Errorf("not a PNG file")
+	}
+	return &pngChunkReader{r: r}, nil
+}
+
+// next は、次のチャンクの長さと種類を読み込みます。
+func (c *pngChunkReader) next() (uint32, string, error) {
+	if _, err := io.ReadFull(c.r, c.buf[:8]); err != nil {
+		if err == io.EOF {
+			return 0, "", io.ErrUnexpectedEOF
+		}
+		return 0, "", err
+	}
+	return binary.BigEndian.Uint32(c.buf[:4]), string(c.buf[4:8]), nil
+}
+
+// readData は、チャンクのデータを読み込み、CRC を検証します。
+func (c *pngChunkReader) readData(chunkType string, length uint32) ([]byte, error) {
+	if length > maxPNGChunkLength {
+		return nil, fmt.Errorf("%s chunk is too large (%d bytes)", chunkType, length)
+	}
+	data := make([]byte, length)
+	if _, err := io.ReadFull(c.r, data); err != nil {
+		return nil, err
+	}
+
+	crc := crc32.NewIEEE()
+	crc.Write([]byte(chunkType))
+	crc.Write(data)
+	if err := c.verifyCRC(chunkType, crc); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// verifyCRC は、チャンク末尾の CRC を読み込み、計算済みの値と比較します。
+func (c *pngChunkReader) verifyCRC(chunkType string, crc hash.Hash32) error {
+	if _, err := io.ReadFull(c.r, c.buf[:4]); err != nil {
+		return err
+	}
+	if binary.BigEndian.Uint32(c.buf[:4]) != crc.Sum32() {
+		return fmt.Errorf("invalid checksum in %s chunk", chunkType)
+	}
+	return nil
+}
+
+// parsePNGHeader は、IHDR チャンクのデータを解析します。
+func parsePNGHeader(data []byte) (pngHeader, error) {
+	if len(data) != 13 {
+		return pngHeader{}, fmt.Errorf("invalid IHDR chunk length: %d", len(data))
+	}
+
+	header := pngHeader{
+		Width:      int(binary.BigEndian.Uint32(data[0:4])),
+		Height:     int(binary.BigEndian.Uint32(data[4:8])),
+		BitDepth:   int(data[8]),
+		ColorType:  int(data[9]),
+		Interlaced: data[12] != 0,
+	}
+	if header.Width <= 0 || header.Height <= 0 {
+		return pngHeader{}, fmt.Errorf("invalid image size: %dx%d", header.Width, header.Height)
+	}
+
+	valid := false
+	switch header.ColorType {
+	case pngColorGray:
+		valid = header.BitDepth == 1 || header.BitDepth == 2 || header.BitDepth == 4 || header.BitDepth == 8 || header.BitDepth == 16
+	case pngColorPalette:
+		valid = header.BitDepth == 1 || header.BitDepth == 2 || header.BitDepth == 4 || header.BitDepth == 8
+	case pngColorRGB, pngColorGrayAlpha, pngColorRGBA:
+		valid = header.BitDepth == 8 || header.BitDepth == 16
+	}
+	if !valid {
+		return pngHeader{}, fmt.Errorf("unsupported color type %d with bit depth %d", header.ColorType, header.BitDepth)
+	}
+
+	return header, nil
+}
+
+// チャンネル数
+func (h pngHeader) channels() int {
+	switch h.ColorType {
+	case pngColorRGB:
+		return 3
+	case pngColorGrayAlpha:
+		return 2
+	case pngColorRGBA:
+		return 4
+	default:
+		return 1
+	}
+}
+
+// 1 行あたりのバイト数（フィルタータイプのバイトを除く）
+func (h pngHeader) rowBytes() int {
+	return (h.Width*h.channels()*h.BitDepth + 7) / 8
+}
+
+// フィルター処理で参照する 1 ピクセルあたりのバイト数（最低 1）
+func (h pngHeader) filterBytesPerPixel() int {
+	return max(1, h.channels()*h.BitDepth/8)
+}
+
+// idatReader は、連続する IDAT チャンクのデータを 1 つのストリームとして読み込みます。
+type idatReader struct {
+	c         *pngChunkReader
+	remaining uint32
+	crc       hash.Hash32
+	done      bool
+}
+
+func (r *idatReader) Read(p []byte) (int, error) {
+	for r.remaining == 0 {
+		if r.done {
+			return 0, io.EOF
+		}
+
+		// 現在の IDAT チャンクを読み終えたので、CRC を検証して次のチャンクへ進む
+		if err := r.c.verifyCRC("IDAT", r.crc); err != nil {
+			return 0, err
+		}
+		length, chunkType, err := r.c.next()
+		if err != nil {
+			return 0, err
+		}
+		if chunkType != "IDAT" {
+			// IDAT 以降のチャンクは使用しないため、ここで読み込みを終える
+			r.done = true
+			return 0, io.EOF
+		}
+		r.remaining = length
+		r.crc.Reset()
+		r.crc.Write([]byte(chunkType))
+	}
+
+	if uint32(len(p)) > r.remaining {
+		p = p[:r.remaining]
+	}
+	n, err := r.c.r.Read(p)
+	r.crc.Write(p[:n])
+	r.remaining -= uint32(n)
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return n, err
+}
+
+// decodePNGScaled は、PNG 画像を 1 行ずつ読み込みながら crop の範囲を切り出し、
+// 最大 maxWidth x maxHeight の大きさに平均化（ボックスフィルター）で縮小した画像を返します。
+// 画像全体をメモリに展開しないため、巨大な画像でもメモリ使用量は出力サイズに比例する程度に抑えられます。
+// crop が空の場合は画像全体を対象とします。インターレースされた PNG の場合は errPNGInterlaced を返します。
+func decodePNGScaled(r io.Reader, crop image.Rectangle, maxWidth, maxHeight int) (image.Image, error) {
+	c, err := newPNGChunkReader(bufio.NewReader(r))
+	if err != nil {
+		return nil, err
+	}
+
+	var header pngHeader
+	var palette color.Palette
+	seenHeader := false
+	for {
+		length, chunkType, err := c.next()
+		if err != nil {
+			return nil, err
+		}
+
+		if chunkType == "IDAT" {
+			if !seenHeader {
+				return nil, fmt.Errorf("IDAT chunk found before IHDR chunk")
+			}
+			if header.ColorType == pngColorPalette && palette == nil {
+				return nil, fmt.Errorf("PLTE chunk not found")
+			}
+			crc := crc32.NewIEEE()
+			crc.Write([]byte(chunkType))
+			idat := &idatReader{c: c, remaining: length, crc: crc}
+			return scalePNGRows(idat, header, palette, crop, maxWidth, maxHeight)
+		}
+
+		data, err := c.readData(chunkType, length)
+		if err != nil {
+			return nil, err
+		}
+
+		switch chunkType {
+		case "IHDR":
+			header, err = parsePNGHeader(data)
+			if err != nil {
+				return nil, err
+			}
+			if header.Interlaced {
+				return nil, errPNGInterlaced
+			}
+			seenHeader = true
+		case "PLTE":
+			if len(data)%3 != 0 || len(data) == 0 || len(data)/3 > 256 {
+				return nil, fmt.Errorf("invalid PLTE chunk length: %d", len(data))
+			}
+			palette = make(color.Palette, len(data)/3)
+			for i := range palette {
+				palette[i] = color.NRGBA{R: data[3*i], G: data[3*i+1], B: data[3*i+2], A: 0xff}
+			}
+		case "tRNS":
+			// パレットの透明度のみ反映する。グレースケール・RGB のカラーキーは無視する
+			if header.ColorType == pngColorPalette {
+				for i := 0; i < len(data) && i < len(palette); i++ {
+					rgba := palette[i].(color.NRGBA)
+					rgba.A = data[i]
+					palette[i] = rgba
+				}
+			}
+		case "IEND":
+			return nil, fmt.Errorf("IDAT chunk not found")
+		}
+	}
+}
+
+// scalePNGRows は、IDAT のデータを 1 行ずつ展開し、縮小した画像を生成します。
+func scalePNGRows(idat io.Reader, header pngHeader, palette color.Palette, crop image.Rectangle, maxWidth, maxHeight int) (image.Image, error) {
+	bounds := image.Rect(0, 0, header.Width, header.Height)
+	if crop.Empty() {
+		crop = bounds
+	}
+	crop = crop.Intersect(bounds)
+	if crop.Empty() {
+		return nil, fmt.Errorf("crop rectangle is outside of the image")
+	}
+
+	cropWidth, cropHeight := crop.Dx(), crop.Dy()
+	destWidth := max(1, min(cropWidth, maxWidth))
+	destHeight := max(1, min(cropHeight, maxHeight))
+	dest := image.NewRGBA(image.Rect(0, 0, destWidth, destHeight))
+
+	// 元画像の x 座標から縮小後の x 座標への対応表
+	destX := make([]int, cropWidth)
+	for x := range destX {
+		destX[x] = x * destWidth / cropWidth
+	}
+
+	// 縮小後の 1 行分の画素値の合計と画素数
+	sums := make([]uint64, destWidth*4)
+	counts := make([]uint64, destWidth)
+	currentY := 0
+	flush := func() {
+		offset := dest.PixOffset(0, currentY)
+		for x := 0; x < destWidth; x++ {
+			n := counts[x]
+			if n == 0 {
+				continue
+			}
+			for ch := 0; ch < 4; ch++ {
+				dest.Pix[offset+x*4+ch] = uint8(sums[x*4+ch] / n >> 8)
+				sums[x*4+ch] = 0
+			}
+			counts[x] = 0
+		}
+	}
+
+	zr, err := zlib.NewReader(idat)
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+
+	rowBytes := header.rowBytes()
+	bpp := header.filterBytesPerPixel()
+	current := make([]byte, rowBytes+1)
+	previous := make([]byte, rowBytes+1)
+
+	for y := 0; y < crop.Max.Y; y++ {
+		if _, err := io.ReadFull(zr, current); err != nil {
+			return nil, fmt.Errorf("failed to read row %d: %w", y, err)
+		}
+		if err := unfilterPNGRow(current[0], current[1:], previous[1:], bpp); err != nil {
+			return nil, err
+		}
+
+		if y >= crop.Min.Y {
+			dy := (y - crop.Min.Y) * destHeight / cropHeight
+			if dy != currentY {
+				flush()
+				currentY = dy
+			}
+
+			row := current[1:]
+			for x := crop.Min.X; x < crop.Max.X; x++ {
+				r, g, b, a, err := pngPixelAt(header, palette, row, x)
+				if err != nil {
+					return nil, err
+				}
+				dx := destX[x-crop.Min.X]
+				sums[dx*4] += uint64(r)
+				sums[dx*4+1] += uint64(g)
+				sums[dx*4+2] += uint64(b)
+				sums[dx*4+3] += uint64(a)
+				counts[dx]++
+			}
+		}
+
+		current, previous = previous, current
+	}
+	flush()
+
+	return dest, nil
+}
+
+// unfilterPNGRow は、PNG のフィルターを解除します。
+func unfilterPNGRow(filter byte, current, previous []byte, bpp int) error {
+	switch filter {
+	case 0:
+		// None
+	case 1:
+		// Sub
+		for i := bpp; i < len(current); i++ {
+			current[i] += current[i-bpp]
+		}
+	case 2:
+		// Up
+		for i := range current {
+			current[i] += previous[i]
+		}
+	case 3:
+		// Average
+		for i := range current {
+			var left byte
+			if i >= bpp {
+				left = current[i-bpp]
+			}
+			current[i] += byte((int(left) + int(previous[i])) / 2)
+		}
+	case 4:
+		// Paeth
+		for i := range current {
+			var left, upperLeft byte
+			if i >= bpp {
+				left = current[i-bpp]
+				upperLeft = previous[i-bpp]
+			}
+			current[i] += paethPredictor(left, previous[i], upperLeft)
+		}
+	default:
+		return fmt.Errorf("invalid filter type: %d", filter)
+	}
+	return nil
+}
+
+func paethPredictor(a, b, c byte) byte {
+	p := int(a) + int(b) - int(c)
+	pa := abs(p - int(a))
+	pb := abs(p - int(b))
+	pc := abs(p - int(c))
+	if pa <= pb && pa <= pc {
+		return a
+	}
+	if pb <= pc {
+		return b
+	}
+	return c
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+// pngPixelAt は、展開済みの 1 行から x 番目のピクセルを、アルファ乗算済みの 16 ビット値として取得します。
+func pngPixelAt(header pngHeader, palette color.Palette, row []byte, x int) (r, g, b, a uint32, err error) {
+	switch header.ColorType {
+	case pngColorPalette:
+		index := pngSampleAt(row, x, header.BitDepth)
+		if int(index) >= len(palette) {
+			return 0, 0, 0, 0, fmt.Errorf("palette index %d out of range", index)
+		}
+		r, g, b, a = palette[index].RGBA()
+		return r, g, b, a, nil
+	case pngColorGray:
+		v := pngSampleAt(row, x, header.BitDepth)
+		switch header.BitDepth {
+		case 16:
+		case 8:
+			v |= v << 8
+		default:
+			// 1, 2, 4 ビットを 16 ビットへ拡張する
+			v = v * 0xffff / (1<<header.BitDepth - 1)
+		}
+		return v, v, v, 0xffff, nil
+	}
+
+	// 8 ビットまたは 16 ビットの各チャンネルを 16 ビット値として読み込む
+	channels := header.channels()
+	sample := func(ch int) uint32 {
+		if header.BitDepth == 16 {
+			offset := (x*channels + ch) * 2
+			return uint32(row[offset])<<8 | uint32(row[offset+1])
+		}
+		v := uint32(row[x*channels+ch])
+		return v<<8 | v
+	}
+
+	var c color.NRGBA64
+	switch header.ColorType {
+	case pngColorGrayAlpha:
+		v := uint16(sample(0))
+		c = color.NRGBA64{R: v, G: v, B: v, A: uint16(sample(1))}
+	case pngColorRGB:
+		c = color.NRGBA64{R: uint16(sample(0)), G: uint16(sample(1)), B: uint16(sample(2)), A: 0xffff}
+	case pngColorRGBA:
+		c = color.NRGBA64{R: uint16(sample(0)), G: uint16(sample(1)), B: uint16(sample(2)), A: uint16(sample(3))}
+	}
+	r, g, b, a = c.RGBA()
+	return r, g, b, a, nil
+}
+
+// pngSampleAt は、1 チャンネルの画像（グレースケール・パレット）の x 番目のサンプル値を取得します。
+func pngSampleAt(row []byte, x, bitDepth int) uint32 {
+	switch bitDepth {
+	case 16:
+		return uint32(row[x*2])<<8 | uint32(row[x*2+1])
+	case 8:
+		return uint32(row[x])
+	default:
+		bitOffset := x * bitDepth
+		shift := 8 - bitDepth - bitOffset%8
+		mask := byte(1<<bitDepth - 1)
+		return uint32(row[bitOffset/8] >> shift & mask)
+	}
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"os"
+	"testing"
+)
+
+// writeTestPNGChunk writes a single PNG chunk with its CRC.
+func writeTestPNGChunk(buf *bytes.Buffer, chunkType string, data []byte) {
+	var header [8]byte
+	binary.BigEndian.PutUint32(header[:4], uint32(len(data)))
+	copy(header[4:], chunkType)
+	buf.Write(header[:])
+	buf.Write(data)
+
+	crc := crc32.NewIEEE()
+	crc.Write([]byte(chunkType))
+	crc.Write(data)
+	var sum [4]byte
+	binary.BigEndian.PutUint32(sum[:], crc.Sum32())
+	buf.Write(sum[:])
+}
+
+// writeLargeTestPNG writes an 8-bit RGB PNG of the given size without holding the whole image in memory.
+// Each row is a horizontal gradient, so the file stays small while the decoded image is huge.
+func writeLargeTestPNG(tb testing.TB, path string, width, height int) {
+	tb.Helper()
+
+	f, err := os.Create(path)
+	if err != nil {
+		tb.Fatalf("Failed to create file: %v", err)
+	}
+	defer f.Close()
+
+	var buf bytes.Buffer
+	buf.WriteString(pngSignature)
+	ihdr := make([]byte, 13)
+	binary.BigEndian.PutUint32(ihdr[0:4], uint32(width))
+	binary.BigEndian.PutUint32(ihdr[4:8], uint32(height))
+	ihdr[8] = 8
+	ihdr[9] = pngColorRGB
+	writeTestPNGChunk(&buf, "IHDR", ihdr)
+
+	// Compress rows one by one and flush IDAT chunks as the buffer grows
+	var idat bytes.Buffer
+	zw, _ := zlib.NewWriterLevel(&idat, zlib.BestSpeed)
+	row := make([]byte, 1+width*3)
+	for x := 0; x < width; x++ {
+		row[1+x*3] = byte(x * 255 / width)
+		row[1+x*3+1] = 0x80
+		row[1+x*3+2] = 0x40
+	}
+	for y := 0; y < height; y++ {
+		zw.Write(row)
+		if idat.Len() > 1<<20 {
+			writeTestPNGChunk(&buf, "IDAT", idat.Bytes())
+			idat.Reset()
+			f.Write(buf.Bytes())
+			buf.Reset()
+		}
+	}
+	zw.Close()
+	writeTestPNGChunk(&buf, "IDAT", idat.Bytes())
+	writeTestPNGChunk(&buf, "IEND", nil)
+	if _, err := f.Write(buf.Bytes()); err != nil {
+		tb.Fatalf("Failed to write file: %v", err)
+	}
+}
+
+// filledImage fills img with a single color and returns it.
+func filledImage(img draw.Image, c color.Color) image.Image {
+	draw.Draw(img, img.Bounds(), image.NewUniform(c), image.Point{}, draw.Src)
+	return img
+}
+
+// Test decodePNGScaled function with every supported color type
+func TestDecodePNGScaled(t *testing.T) {
+	rect := image.Rect(0, 0, 40, 20)
+	palette := color.Palette{color.NRGBA{0, 0, 0, 0xff}, color.NRGBA{0x20, 0x40, 0x60, 0xff}}
+	tests := []struct {
+		name string
+		img  image.Image
+		want color.RGBA
+	}{
+		{"Gray", filledImage(image.NewGray(rect), color.Gray{0x80}), color.RGBA{0x80, 0x80, 0x80, 0xff}},
+		{"Gray16", filledImage(image.NewGray16(rect), color.Gray16{0x8080}), color.RGBA{0x80, 0x80, 0x80, 0xff}},
+		{"RGB", filledImage(image.NewRGBA(rect), color.RGBA{0x10, 0x20, 0x30, 0xff}), color.RGBA{0x10, 0x20, 0x30, 0xff}},
+		{"RGBA", filledImage(image.NewNRGBA(rect), color.NRGBA{0xff, 0x00, 0x00, 0x80}), color.RGBA{0x80, 0x00, 0x00, 0x80}},
+		{"RGBA64", filledImage(image.NewNRGBA64(rect), color.NRGBA64{0x1010, 0x2020, 0x3030, 0xffff}), color.RGBA{0x10, 0x20, 0x30, 0xff}},
+		{"Paletted", filledImage(image.NewPaletted(rect, palette), palette[1]), color.RGBA{0x20, 0x40, 0x60, 0xff}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := png.Encode(&buf, tt.img); err != nil {
+				t.Fatalf("Failed to encode PNG: %v", err)
+			}
+
+			got, err := decodePNGScaled(&buf, image.Rect(10, 0, 30, 20), 10, 10)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if got.Bounds().Dx() != 10 || got.Bounds().Dy() != 10 {
+				t.Fatalf("Expected scaled image to be 10x10, got %dx%d", got.Bounds().Dx(), got.Bounds().Dy())
+			}
+			if c := color.RGBAModel.Convert(got.At(5, 5)).(color.RGBA); c != tt.want {
+				t.Errorf("Expected pixel %v, got %v", tt.want, c)
+			}
+		})
+	}
+}
+
+// Test that decodePNGScaled averages the source pixels
+func TestDecodePNGScaledAverages(t *testing.T) {
+	// Alternating black and white columns average to gray
+	src := image.NewGray(image.Rect(0, 0, 8, 8))
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x += 2 {
+			src.SetGray(x, y, color.Gray{0xff})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, src); err != nil {
+		t.Fatalf("Failed to encode PNG: %v", err)
+	}
+
+	got, err := decodePNGScaled(&buf, image.Rectangle{}, 4, 4)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	c := color.GrayModel.Convert(got.At(1, 1)).(color.Gray)
+	if c.Y < 0x7e || c.Y > 0x81 {
+		t.Errorf("Expected averaged gray around 0x7f, got %#x", c.Y)
+	}
+}
+
+// Test that interlaced PNGs are reported so the caller can fall back
+func TestDecodePNGScaledInterlaced(t *testing.T) {
+	var buf bytes.Buffer
+	buf.WriteString(pngSignature)
+	ihdr := make([]byte, 13)
+	binary.BigEndian.PutUint32(ihdr[0:4], 4)
+	binary.BigEndian.PutUint32(ihdr[4:8], 4)
+	ihdr[8] = 8
+	ihdr[9] = pngColorGray
+	ihdr[12] = 1
+	writeTestPNGChunk(&buf, "IHDR", ihdr)
+
+	_, err := decodePNGScaled(&buf, image.Rectangle{}, 2, 2)
+	if !errors.Is(err, errPNGInterlaced) {
+		t.Fatalf("Expected errPNGInterlaced, got %v", err)
+	}
+}
+
+// writeInterlacedTestPNG writes an interlaced 8-bit grayscale PNG of the given size to path.
+// Only a 1x1 image gets pixel data (its single Adam7 pass is the same as a non-interlaced row),
+// larger images have no IDAT chunk as their size is all that is read before decoding.
+func writeInterlacedTestPNG(t *testing.T, path string, width, height int) {
+	t.Helper()
+	var buf bytes.Buffer
+	buf.WriteString(pngSignature)
+	ihdr := make([]byte, 13)
+	binary.BigEndian.PutUint32(ihdr[0:4], uint32(width))
+	binary.BigEndian.PutUint32(ihdr[4:8], uint32(height))
+	ihdr[8] = 8
+	ihdr[9] = pngColorGray
+	ihdr[12] = 1
+	writeTestPNGChunk(&buf, "IHDR", ihdr)
+	if width == 1 && height == 1 {
+		var idat bytes.Buffer
+		zw := zlib.NewWriter(&idat)
+		zw.Write([]byte{0, 0x80})
+		zw.Close()
+		writeTestPNGChunk(&buf, "IDAT", idat.Bytes())
+		writeTestPNGChunk(&buf, "IEND", nil)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Test that a corrupted chunk is rejected
+func TestDecodePNGScaledInvalidChecksum(t *testing.T) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, 4, 4))); err != nil {
+		t.Fatalf("Failed to encode PNG: %v", err)
+	}
+	data := buf.Bytes()
+	// Corrupt the width in IHDR without updating the CRC
+	data[len(pngSignature)+8+3] ^= 0xff
+
+	if _, err := decodePNGScaled(bytes.NewReader(data), image.Rectangle{}, 2, 2); err == nil {
+		t.Fatalf("Expected an error, got nil")
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"errors"
+	"time"
+)
+
+// 終了コード。スクリプトから実行結果を判別できるよう、失敗の原因ごとに異なる値を返す
+const (
+	exitOK = 0
+	// exitFailure は、以下のいずれにも当てはまらないエラー
+	exitFailure = 1
+	// exitUsage は、コマンドや引数が正しくない場合
+	exitUsage = 2
+	// exitConfigError は、設定ファイルや環境変数の設定値が正しくない場合
+	exitConfigError = 3
+	// exitNoSource は、ソースフォルダを取得できない、または読み込めない場合
+	exitNoSource = 4
+	// exitNoDestination は、保存先のフォルダを取得できない場合
+	exitNoDestination = 5
+	// exitNoFiles は、条件を満たすソース画像が 1 つもない場合
+	exitNoFiles = 6
+	// exitWriteFailed は、画像の読み込み・変換・スプラッシュスクリーンの書き込みに失敗した場合
+	exitWriteFailed = 7
+)
+
+// exitError は、終了コードを持つエラーです。
+type exitError struct {
+	Code int
+	Err  error
+}
+
+func (e *exitError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *exitError) Unwrap() error {
+	return e.Err
+}
+
+// withExitCode は、エラーに終了コードを付けます。err が nil の場合は nil を返します。
+func withExitCode(code int, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &exitError{Code: code, Err: err}
+}
+
+// exitCodeOf は、エラーに対応する終了コードを返します。
+func exitCodeOf(err error) int {
+	if err == nil {
+		return exitOK
+	}
+	var exitErr *exitError
+	if errors.As(err, &exitErr) {
+		return exitErr.Code
+	}
+	if errors.Is(err, errUsage) {
+		return exitUsage
+	}
+	return exitFailure
+}
+
+// runResult は、-json を指定して run コマンドを実行した場合に出力する実行結果です。
+type runResult struct {
+	// Status は、実行結果（ok または error）
+	Status string `json:"status"`
+	// Source は、選択した画像のパス
+	Source string `json:"source,omitempty"`
+	// SourceWidth・SourceHeight は、選択した画像の大きさ
+	SourceWidth  int `json:"source_width,omitempty"`
+	SourceHeight int `json:"source_height,omitempty"`
+	// Destination は、スプラッシュスクリーンファイルのパス
+	Destination string `json:"destination,omitempty"`
+	// Width・Height は、スプラッシュスクリーンの大きさ
+	Width  int `json:"width,omitempty"`
+	Height int `json:"height,omitempty"`
+	// DurationMs は、実行にかかった時間（ミリ秒）
+	DurationMs int64 `json:"duration_ms"`
+	// Error は、エラーメッセージ
+	Error string `json:"error,omitempty"`
+	// ExitCode は、終了コード
+	ExitCode int `json:"exit_code"`
+}
+
+// finish は、実行結果にエラーと実行時間を設定します。
+func (r *runResult) finish(start time.Time, err error) {
+	r.DurationMs = time.Since(start).Milliseconds()
+	r.ExitCode = exitCodeOf(err)
+	r.Status = "ok"
+	if err != nil {
+		r.Status = "error"
+		r.Error = err.Error()
+	}
+}
+
+// errorResult は、-json を指定したコマンドが失敗した場合に出力する実行結果です。
+type errorResult struct {
+	Status   string `json:"status"`
+	Error    string `json:"error"`
+	ExitCode int    `json:"exit_code"`
+	// Problems は、設定値の問題の一覧。設定値の問題で失敗した場合のみ出力する
+	Problems []configProblem `json:"problems,omitempty"`
+}
+
+// newErrorResult は、エラーから実行結果を作成します。
+func newErrorResult(err error) errorResult {
+	result := errorResult{Status: "error", Error: err.Error(), ExitCode: exitCodeOf(err)}
+	var configErr *configError
+	if errors.As(err, &configErr) {
+		result.Problems = configErr.Problems
+	}
+	return result
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"reflect"
+)
+
+// JSON Schema のバージョン。VS Code の YAML 拡張機能が対応している draft-07 を使用する
+const jsonSchemaVersion = "http://json-schema.org/draft-07/schema#"
+
+// jsonSchema は、JSON Schema のスキーマです。設定値の構造体から生成するために必要なキーワードのみを持ちます。
+type jsonSchema struct {
+	Schema      string `json:"$schema,omitempty"`
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	Type        string `json:"type,omitempty"`
+	// Format は、文字列の形式。time.Duration の項目では "duration" とする（例: 30s、1h30m）
+	Format     string                 `json:"format,omitempty"`
+	Default    any                    `json:"default,omitempty"`
+	Properties map[string]*jsonSchema `json:"properties,omitempty"`
+	Required   []string               `json:"required,omitempty"`
+	// AdditionalProperties は、構造体の場合は false（不明なキーを許可しない）、マップの場合は値のスキーマ
+	AdditionalProperties any         `json:"additionalProperties,omitempty"`
+	Items                *jsonSchema `json:"items,omitempty"`
+}
+
+// generateConfigSchema は、Config 構造体の yaml・help・default・required タグから、設定ファイルの JSON Schema を生成します。
+func generateConfigSchema() *jsonSchema {
+	schema := typeSchema(reflect.TypeOf(Config{}))
+	schema.Schema = jsonSchemaVersion
+	schema.Title = "splashscreen-changer configuration"
+	// include は Config のフィールドではなく、設定ファイルを読み込む際に取り除かれる
+	schema.Properties[includeKey] = &jsonSchema{
+		Description: "Paths of configuration files to merge before this file (relative to this file). Values in this file take priority",
+		Type:        "array",
+		Items:       &jsonSchema{Type: "string"},
+	}
+	return schema
+}
+
+// typeSchema は、型のスキーマを生成します。構造体はフィールドごとのプロパティを持つオブジェクトとします。
+func typeSchema(t reflect.Type) *jsonSchema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch {
+	case t == durationType:
+		return &jsonSchema{Type: "string", Format: "duration"}
+	case reflect.PointerTo(t).Implements(textUnmarshalerType):
+		return &jsonSchema{Type: "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return &jsonSchema{Type: "string"}
+	case reflect.Bool:
+		return &jsonSchema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &jsonSchema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &jsonSchema{Type: "number"}
+	case reflect.Slice, reflect.Array:
+		return &jsonSchema{Type: "array", Items: typeSchema(t.Elem())}
+	case reflect.Map:
+		return &jsonSchema{Type: "object", AdditionalProperties: typeSchema(t.Elem())}
+	case reflect.Struct:
+		schema := &jsonSchema{Type: "object", Properties: map[string]*jsonSchema{}, AdditionalProperties: false}
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			name := getYAMLName(field)
+			if !field.IsExported() || name == "-" {
+				continue
+			}
+			property := typeSchema(field.Type)
+			property.Description = field.Tag.Get("help")
+			if defaultValue, ok := field.Tag.Lookup("default"); ok {
+				property.Default = schemaDefault(field.Type, defaultValue)
+			}
+			// requiredProblems と同じく、required タグは末端の項目のみに適用する。セクションは省略できる（例: destination を省略するとフォルダを自動で検出する）
+			if required, _ := field.Tag.Lookup("required"); required == "true" && !isConfigSection(field.Type) {
+				schema.Required = append(schema.Required, name)
+			}
+			schema.Properties[name] = property
+		}
+		return schema
+	}
+	return &jsonSchema{}
+}
+
+// schemaDefault は、default タグの値を、スキーマの default に使用する値に変換します。
+// 解析できない場合や空文字列の場合は nil を返します。
+func schemaDefault(t reflect.Type, defaultValue string) any {
+	if defaultValue == "" {
+		return nil
+	}
+	value := reflect.New(t).Elem()
+	if err := setFieldFromString(value, defaultValue); err != nil {
+		return nil
+	}
+	// time.Duration や TextUnmarshaler の項目は、設定ファイルと同じく文字列で表す
+	if t == durationType || reflect.PointerTo(t).Implements(textUnmarshalerType) {
+		return defaultValue
+	}
+	return value.Interface()
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+var updateGolden = flag.Bool("update", false, "Update the golden files in testdata")
+
+func TestConfigSchemaGolden(t *testing.T) {
+	var out bytes.Buffer
+	if err := runConfigSchema(&commandContext{Out: &out}, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	golden := filepath.Join("testdata", "config.schema.json")
+	if *updateGolden {
+		if err := os.WriteFile(golden, out.Bytes(), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out.Bytes(), want) {
+		t.Errorf("The schema of Config has changed. Run 'go test -run TestConfigSchemaGolden -update' and review the diff of %s", golden)
+	}
+}
+
+func TestTypeSchema(t *testing.T) {
+	schema := typeSchema(reflect.TypeOf(testNestedConfig{}))
+
+	text := schema.Properties["overlay"].Properties["text"]
+	if text == nil || text.Type != "object" || text.AdditionalProperties != false {
+		t.Fatalf("Expected overlay.text to be a nested object, got %+v", text)
+	}
+	if got := text.Properties["color"]; got.Type != "string" || got.Default != "#ffffff" {
+		t.Errorf("Expected a TextUnmarshaler to be a string, got %+v", got)
+	}
+	if got := text.Properties["fade"]; got.Type != "string" || got.Format != "duration" || got.Default != "500ms" {
+		t.Errorf("Unexpected duration schema: %+v", got)
+	}
+	if len(text.Required) != 1 || text.Required[0] != "content" {
+		t.Errorf("Expected content to be required, got %v", text.Required)
+	}
+
+	limits := schema.Properties["limits"]
+	if got := limits.Properties["sizes"]; got.Type != "array" || got.Items.Type != "integer" {
+		t.Errorf("Unexpected list schema: %+v", got)
+	}
+	data, err := json.Marshal(limits.Properties["weights"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `{"type":"object","additionalProperties":{"type":"integer"}}` {
+		t.Errorf("Unexpected map schema: %s", data)
+	}
+}
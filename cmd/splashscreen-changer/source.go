@@ -0,0 +1,202 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"golang.org/x/exp/rand"
+)
+
+// SourcePath は、source.paths に指定するソースフォルダごとの設定です。
+// 指定されていない項目は、source 直下の設定値を引き継ぎます。
+type SourcePath struct {
+	Path      string   `yaml:"path" help:"Path to the source directory" required:"true"`
+	Recursive *bool    `yaml:"recursive" help:"Whether to search for PNG files recursively. If not specified, source.recursive is used"`
+	Include   []string `yaml:"include" help:"Glob patterns of files to pick. If not specified, source.include is used"`
+	Exclude   []string `yaml:"exclude" help:"Glob patterns of files and folders to skip, in addition to source.exclude"`
+	Weight    *float64 `yaml:"weight" help:"Relative probability of picking an image from this directory. If not specified, 1 is used. 0 excludes the directory"`
+}
+
+// sourceSpec は、設定値から解決したソースフォルダです。
+type sourceSpec struct {
+	Path      string
+	Recursive bool
+	Filter    pathFilter
+	// Weight は、このソースフォルダから画像を選択する相対的な確率
+	Weight float64
+}
+
+// candidate は、選択の候補となるソース画像です。
+type candidate struct {
+	Path string
+	// Source は、画像が見つかったソースフォルダのインデックス
+	Source int
+}
+
+// resolveSources は、設定値からソースフォルダの一覧を返します。
+// source.paths が指定されている場合はそれを使用し、指定されていない場合は getSourcePath で取得した 1 つのフォルダを使用します。
+func resolveSources(config *Config) ([]sourceSpec, error) {
+	if len(config.Source.Paths) == 0 {
+		sourcePath, err := getSourcePath(config)
+		if err != nil {
+			return nil, err
+		}
+		return []sourceSpec{{
+			Path:      sourcePath,
+			Recursive: config.Source.Recursive,
+			Filter:    newPathFilter(config),
+			Weight:    1,
+		}}, nil
+	}
+
+	var sources []sourceSpec
+	for _, path := range config.Source.Paths {
+		source := sourceSpec{
+			Path:      path.Path,
+			Recursive: config.Source.Recursive,
+			Filter:    newPathFilter(config),
+			Weight:    1,
+		}
+		// weight に 0 を指定したソースフォルダは、一時的に無効にしたものとして使用しない
+		if path.Weight != nil {
+			if *path.Weight == 0 {
+				continue
+			}
+			source.Weight = *path.Weight
+		}
+		if path.Recursive != nil {
+			source.Recursive = *path.Recursive
+		}
+		// include はフォルダごとの指定で置き換え、exclude は source 直下の指定に追加する
+		if len(path.Include) > 0 {
+			source.Filter.Include = path.Include
+		}
+		source.Filter.Exclude = append(append([]string{}, source.Filter.Exclude...), path.Exclude...)
+		sources = append(sources, source)
+	}
+	return sources, nil
+}
+
+// checkSourcePaths は、source.paths の各設定値をチェックします。
+func checkSourcePaths(paths []SourcePath) []configProblem {
+	var problems []configProblem
+	disabled := 0
+	for i, path := range paths {
+		key := fmt.Sprintf("source.paths[%d]", i)
+		if path.Path == "" {
+			problems = append(problems, newConfigProblem(key+".path", fmt.Errorf("%s.path is required but was empty", key)))
+		} else if _, err := os.Stat(path.Path); err != nil {
+			problems = append(problems, newConfigProblem(key+".path", withExitCode(exitNoSource, fmt.Errorf("source path '%s' does not exist", path.Path))))
+		}
+		if path.Weight != nil && *path.Weight < 0 {
+			problems = append(problems, newConfigProblem(key+".weight", fmt.Errorf("%s.weight must not be negative", key)))
+		}
+		if path.Weight != nil && *path.Weight == 0 {
+			disabled++
+		}
+		if err := (pathFilter{Include: path.Include, Exclude: path.Exclude}).validate(); err != nil {
+			problems = append(problems, newConfigProblem(key, fmt.Errorf("%s: %w", key, err)))
+		}
+	}
+	if len(paths) > 0 && disabled == len(paths) {
+		problems = append(problems, newConfigProblem("source.paths", errors.New("source.paths must have at least one directory with a weight other than 0")))
+	}
+	return problems
+}
+
+// listCandidates は、インデックスを使用して、すべてのソースフォルダの PNG ファイルをリストします。
+// 複数のソースフォルダで同じファイルが見つかった場合は、先に指定されたソースフォルダのものとして 1 つにまとめます。
+func listCandidates(sources []sourceSpec, index *libraryIndex) ([]candidate, error) {
+	var candidates []candidate
+	seen := map[string]bool{}
+	for i, source := range sources {
+		files, err := index.listPNGFiles(source.Path, source.Recursive, source.Filter)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list files in %s: %w", source.Path, err)
+		}
+
+		for _, file := range files {
+			key := fileKey(file)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			candidates = append(candidates, candidate{Path: file, Source: i})
+		}
+	}
+	return candidates, nil
+}
+
+// fileKey は、同じファイルを指すパスが同じ値になるよう正規化したキーを返します。
+func fileKey(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	path = filepath.Clean(path)
+	if runtime.GOOS == "windows" {
+		// Windows のファイルシステムは大文字小文字を区別しない
+		path = strings.ToLower(path)
+	}
+	return path
+}
+
+// candidatePaths は、候補のパスの一覧を返します。
+func candidatePaths(candidates []candidate) []string {
+	paths := make([]string, len(candidates))
+	for i, c := range candidates {
+		paths[i] = c.Path
+	}
+	return paths
+}
+
+// errNoPNGFiles は、選択できる画像が 1 つもない場合のエラーです。
+var errNoPNGFiles = errors.New("no PNG files found")
+
+// pickCandidate は、ソースフォルダの重みに従ってソースフォルダを選択し、その中からランダムに 1 つの画像を選択します。
+// 候補が 1 つもないソースフォルダは選択されません。
+func pickCandidate(candidates []candidate, sources []sourceSpec) (string, error) {
+	if len(candidates) == 0 {
+		return "", errNoPNGFiles
+	}
+
+	// ソースフォルダごとに候補をまとめる
+	bySource := map[int][]string{}
+	totalWeight := 0.0
+	for _, c := range candidates {
+		if _, ok := bySource[c.Source]; !ok {
+			totalWeight += sources[c.Source].Weight
+		}
+		bySource[c.Source] = append(bySource[c.Source], c.Path)
+	}
+
+	// 重みのないソースフォルダのみの場合は、ソースフォルダを区別せずに選択する
+	if totalWeight <= 0 {
+		return pickRandomFile(candidatePaths(candidates))
+	}
+
+	rand.Seed(uint64(time.Now().UnixNano())) // 現在時刻をシードにして乱数を初期化
+	target := rand.Float64() * totalWeight
+	for i := range sources {
+		files, ok := bySource[i]
+		if !ok {
+			continue
+		}
+		target -= sources[i].Weight
+		if target < 0 {
+			return pickRandomFile(files)
+		}
+	}
+
+	// 浮動小数点の誤差で選択されなかった場合は、最後のソースフォルダから選択する
+	for i := len(sources) - 1; i >= 0; i-- {
+		if files, ok := bySource[i]; ok {
+			return pickRandomFile(files)
+		}
+	}
+	return "", errNoPNGFiles
+}
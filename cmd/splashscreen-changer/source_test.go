@@ -0,0 +1,139 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// weightOf returns a pointer to the weight of a source path.
+func weightOf(weight float64) *float64 {
+	return &weight
+}
+
+func TestResolveSources(t *testing.T) {
+	tmpDir := t.TempDir()
+	recursive := false
+
+	var config Config
+	config.Source.Recursive = true
+	config.Source.Include = []string{"*.png"}
+	config.Source.Exclude = []string{"thumbnails"}
+	config.Source.SkipHidden = true
+	config.Source.Paths = []SourcePath{
+		{Path: filepath.Join(tmpDir, "a")},
+		{Path: filepath.Join(tmpDir, "b"), Recursive: &recursive, Include: []string{"best/**"}, Exclude: []string{"edits"}, Weight: weightOf(3)},
+		// A weight of 0 disables the directory
+		{Path: filepath.Join(tmpDir, "c"), Weight: weightOf(0)},
+	}
+
+	sources, err := resolveSources(&config)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(sources) != 2 {
+		t.Fatalf("Expected 2 sources, got %d", len(sources))
+	}
+
+	// Unspecified settings are inherited from source
+	if !sources[0].Recursive || sources[0].Weight != 1 || !sources[0].Filter.SkipHidden {
+		t.Errorf("Expected first source to inherit settings, got %+v", sources[0])
+	}
+	if len(sources[0].Filter.Include) != 1 || sources[0].Filter.Include[0] != "*.png" {
+		t.Errorf("Expected first source to inherit include patterns, got %v", sources[0].Filter.Include)
+	}
+
+	// Per-source settings override or extend the shared ones
+	if sources[1].Recursive || sources[1].Weight != 3 {
+		t.Errorf("Expected second source to override settings, got %+v", sources[1])
+	}
+	if len(sources[1].Filter.Include) != 1 || sources[1].Filter.Include[0] != "best/**" {
+		t.Errorf("Expected second source include patterns to be replaced, got %v", sources[1].Filter.Include)
+	}
+	if len(sources[1].Filter.Exclude) != 2 {
+		t.Errorf("Expected second source exclude patterns to be appended, got %v", sources[1].Filter.Exclude)
+	}
+	if len(config.Source.Exclude) != 1 {
+		t.Errorf("Expected shared exclude patterns not to be modified, got %v", config.Source.Exclude)
+	}
+}
+
+func TestListCandidatesDeduplicates(t *testing.T) {
+	tmpDir := t.TempDir()
+	for _, name := range []string{"a.png", "nested/b.png", "other/c.png"} {
+		path := filepath.Join(tmpDir, filepath.FromSlash(name))
+		os.MkdirAll(filepath.Dir(path), os.ModePerm)
+		f, _ := os.Create(path)
+		f.Close()
+	}
+
+	// The second source is nested in the first one, and the third is the same folder written differently
+	sources := []sourceSpec{
+		{Path: tmpDir, Recursive: true, Weight: 1},
+		{Path: filepath.Join(tmpDir, "nested"), Recursive: true, Weight: 1},
+		{Path: filepath.Join(tmpDir, "other", "..", "other"), Recursive: false, Weight: 1},
+	}
+	candidates, err := listCandidates(sources, newLibraryIndex(""))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(candidates) != 3 {
+		t.Fatalf("Expected 3 candidates, got %d: %v", len(candidates), candidates)
+	}
+	for _, c := range candidates {
+		if c.Source != 0 {
+			t.Errorf("Expected %s to belong to the first source, got %d", c.Path, c.Source)
+		}
+	}
+
+	if _, err := listCandidates([]sourceSpec{{Path: filepath.Join(tmpDir, "missing")}}, newLibraryIndex("")); err == nil {
+		t.Errorf("Expected an error for a missing source, got nil")
+	}
+}
+
+func TestPickCandidate(t *testing.T) {
+	sources := []sourceSpec{{Weight: 100}, {Weight: 1}, {Weight: 1}}
+
+	// Sources without candidates are never picked, however large their weight is
+	candidates := []candidate{{Path: "b1.png", Source: 1}, {Path: "b2.png", Source: 1}, {Path: "c1.png", Source: 2}}
+	seen := map[string]bool{}
+	for i := 0; i < 200; i++ {
+		picked, err := pickCandidate(candidates, sources)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		seen[picked] = true
+	}
+	if len(seen) != 3 {
+		t.Errorf("Expected every candidate to be picked at least once, got %v", seen)
+	}
+
+	if _, err := pickCandidate(nil, sources); err == nil {
+		t.Errorf("Expected an error, got nil")
+	}
+}
+
+func TestCheckSourcePaths(t *testing.T) {
+	tmpDir := t.TempDir()
+	tests := []struct {
+		name    string
+		paths   []SourcePath
+		wantErr bool
+	}{
+		{"Valid", []SourcePath{{Path: tmpDir, Weight: weightOf(2)}}, false},
+		{"Disabled", []SourcePath{{Path: tmpDir}, {Path: tmpDir, Weight: weightOf(0)}}, false},
+		{"All disabled", []SourcePath{{Path: tmpDir, Weight: weightOf(0)}}, true},
+		{"Empty path", []SourcePath{{Path: ""}}, true},
+		{"Missing path", []SourcePath{{Path: filepath.Join(tmpDir, "missing")}}, true},
+		{"Negative weight", []SourcePath{{Path: tmpDir, Weight: weightOf(-1)}}, true},
+		{"Bad pattern", []SourcePath{{Path: tmpDir, Exclude: []string{"[a"}}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := checkSourcePaths(tt.paths); (err != nil) != tt.wantErr {
+				t.Errorf("checkSourcePaths() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
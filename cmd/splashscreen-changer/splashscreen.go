@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+// スプラッシュスクリーン画像に書き込むテキストチャンクのキーワード
+const (
+	splashTextSoftware = "Software"
+	splashTextSource   = "splashscreen-changer:source"
+	splashTextPickedAt = "splashscreen-changer:picked_at"
+)
+
+// destination.copy_metadata が有効な場合に、ソース画像からコピーするテキストチャンクのキーワード
+var copiedTextKeywords = []string{"Title", "Author", "Description", "Copyright", "Comment", "XML:com.adobe.xmp"}
+
+// splashScreenInfo は、スプラッシュスクリーン画像に書き込まれた、生成元の情報です。
+type splashScreenInfo struct {
+	// Source は、元の画像のパス
+	Source string `json:"source"`
+	// Destination は、スプラッシュスクリーンファイルのパス
+	Destination string `json:"destination,omitempty"`
+	// PickedAt は、元の画像を選択した日時
+	PickedAt time.Time `json:"picked_at,omitzero"`
+	// Software は、画像を生成したアプリケーションとバージョン
+	Software string `json:"software,omitempty"`
+	// From は、情報の取得元（metadata: 画像のメタデータ、state: 状態ファイル）
+	From string `json:"from,omitempty"`
+}
+
+// splashScreenTexts は、スプラッシュスクリーン画像に書き込むテキストチャンクを返します。
+// copyMetadata が true の場合は、ソース画像の作者などのテキストチャンクもコピーします。
+func splashScreenTexts(srcPath string, pickedAt time.Time, copyMetadata bool) ([]pngText, error) {
+	source, err := filepath.Abs(srcPath)
+	if err != nil {
+		return nil, err
+	}
+
+	texts := []pngText{
+		{Keyword: splashTextSoftware, Text: "splashscreen-changer " + GetAppVersion()},
+		{Keyword: splashTextSource, Text: source},
+		{Keyword: splashTextPickedAt, Text: pickedAt.Format(time.RFC3339)},
+	}
+	if !copyMetadata {
+		return texts, nil
+	}
+
+	_, srcTexts, err := readPNGTextFile(srcPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read metadata of %s: %w", srcPath, err)
+	}
+	for _, text := range srcTexts {
+		if slices.Contains(copiedTextKeywords, text.Keyword) {
+			texts = append(texts, text)
+		}
+	}
+	return texts, nil
+}
+
+// readSplashScreenInfo は、スプラッシュスクリーン画像から生成元の情報を読み込みます。
+// このアプリケーションが生成した画像でない場合はエラーを返します。
+func readSplashScreenInfo(path string) (splashScreenInfo, error) {
+	_, texts, err := readPNGTextFile(path)
+	if err != nil {
+		return splashScreenInfo{}, err
+	}
+
+	var info splashScreenInfo
+	for _, text := range texts {
+		switch text.Keyword {
+		case splashTextSource:
+			info.Source = text.Text
+		case splashTextPickedAt:
+			if t, err := time.Parse(time.RFC3339, text.Text); err == nil {
+				info.PickedAt = t
+			}
+		case splashTextSoftware:
+			info.Software = text.Text
+		}
+	}
+	if info.Source == "" {
+		return splashScreenInfo{}, fmt.Errorf("%s was not generated by splashscreen-changer", path)
+	}
+	return info, nil
+}
+
+// printSplashScreenInfo は、スプラッシュスクリーン画像の生成元の情報を表示します。
+func printSplashScreenInfo(w io.Writer, info splashScreenInfo) {
+	fmt.Fprintf(w, "Source: %s\n", info.Source)
+	if info.Destination != "" {
+		fmt.Fprintf(w, "Destination: %s\n", info.Destination)
+	}
+	if !info.PickedAt.IsZero() {
+		fmt.Fprintf(w, "Picked at: %s\n", info.PickedAt.Format(time.RFC3339))
+	}
+	if info.Software != "" {
+		fmt.Fprintf(w, "Generated by: %s\n", info.Software)
+	}
+	if info.From == "state" {
+		fmt.Fprintln(w, "(from the pick history)")
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestResizePNGFileWritesSplashScreenInfo(t *testing.T) {
+	dir := t.TempDir()
+	srcPath := filepath.Join(dir, "写真.png")
+	writeTestPNG(t, srcPath, 160, 90)
+	destPath := filepath.Join(dir, "SplashScreen.png")
+
+	pickedAt := time.Date(2026, 10, 19, 12, 34, 56, 0, time.UTC)
+	texts, err := splashScreenTexts(srcPath, pickedAt, false)
+	if err != nil {
+		t.Fatalf("splashScreenTexts failed: %v", err)
+	}
+	if err := resizePNGFile(srcPath, destPath, 80, 45, 0, texts...); err != nil {
+		t.Fatalf("resizePNGFile failed: %v", err)
+	}
+
+	// The output must still be a valid PNG
+	f, err := os.Open(destPath)
+	if err != nil {
+		t.Fatalf("Failed to open output: %v", err)
+	}
+	defer f.Close()
+	img, err := png.Decode(f)
+	if err != nil {
+		t.Fatalf("Failed to decode output: %v", err)
+	}
+	if bounds := img.Bounds(); bounds.Dx() != 80 || bounds.Dy() != 45 {
+		t.Errorf("Expected 80x45, got %dx%d", bounds.Dx(), bounds.Dy())
+	}
+
+	info, err := readSplashScreenInfo(destPath)
+	if err != nil {
+		t.Fatalf("readSplashScreenInfo failed: %v", err)
+	}
+	abs, _ := filepath.Abs(srcPath)
+	if info.Source != abs {
+		t.Errorf("Expected source %s, got %s", abs, info.Source)
+	}
+	if !info.PickedAt.Equal(pickedAt) {
+		t.Errorf("Expected picked time %v, got %v", pickedAt, info.PickedAt)
+	}
+	if info.Software != "splashscreen-changer "+GetAppVersion() {
+		t.Errorf("Unexpected software: %s", info.Software)
+	}
+}
+
+func TestSplashScreenTextsCopyMetadata(t *testing.T) {
+	srcPath := filepath.Join("testdata", "VRChat_2026-01-02_03-04-05.678_16x9.png")
+
+	texts, err := splashScreenTexts(srcPath, time.Now(), false)
+	if err != nil {
+		t.Fatalf("splashScreenTexts failed: %v", err)
+	}
+	if len(texts) != 3 {
+		t.Errorf("Expected only the generated texts, got %v", texts)
+	}
+
+	texts, err = splashScreenTexts(srcPath, time.Now(), true)
+	if err != nil {
+		t.Fatalf("splashScreenTexts failed: %v", err)
+	}
+	if len(texts) != 4 || texts[3].Keyword != "XML:com.adobe.xmp" {
+		t.Fatalf("Expected the XMP of the source to be copied, got %v", texts)
+	}
+
+	// The copied XMP keeps the world information in the output
+	dir := t.TempDir()
+	destPath := filepath.Join(dir, "SplashScreen.png")
+	if err := resizePNGFile(srcPath, destPath, 16, 9, 0, texts...); err != nil {
+		t.Fatalf("resizePNGFile failed: %v", err)
+	}
+	meta, err := readScreenshotMetadata(destPath)
+	if err != nil {
+		t.Fatalf("readScreenshotMetadata failed: %v", err)
+	}
+	if meta.WorldName != "The Black Cat" || meta.Author.Name != "Photographer" {
+		t.Errorf("Expected copied world and author, got %+v", meta)
+	}
+}
+
+func TestReadSplashScreenInfoNotGenerated(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "SplashScreen.png")
+	writeTestPNG(t, path, 8, 8)
+
+	if _, err := readSplashScreenInfo(path); err == nil {
+		t.Error("Expected an error for an image without splash screen metadata")
+	}
+}
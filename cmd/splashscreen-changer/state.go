@@ -0,0 +1,149 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// 状態ファイルに保存する選択履歴の最大件数。超えた場合は古いものから削除する
+const maxHistoryEntries = 1000
+
+// appState は、実行をまたいで保持するアプリケーションの状態です。
+type appState struct {
+	// History は、これまでに選択した画像の履歴。古いものから順に並ぶ
+	History []pickRecord `json:"history"`
+	// NextPick は、次回の実行で 1 回だけ使用する画像（pick コマンドで設定）
+	NextPick string `json:"next_pick,omitempty"`
+	// Pin は、期限まで使い続ける画像（pin コマンドで設定）
+	Pin *pinnedImage `json:"pin,omitempty"`
+
+	path string
+	// corrupt は、状態ファイルが壊れていて読み込めなかったか。保存する前に、壊れたファイルを別の名前で残す
+	corrupt bool
+}
+
+// pickRecord は、1 回の画像の選択の記録です。
+type pickRecord struct {
+	Source      string    `json:"source"`
+	Destination string    `json:"destination"`
+	PickedAt    time.Time `json:"picked_at"`
+}
+
+// loadState は、指定されたパスから状態ファイルを読み込みます。ファイルが存在しない場合は空の状態を返します。
+// 状態ファイルが壊れている場合は、エラーとともに空の状態を返します。この状態を保存すると、壊れたファイルは .bad を付けた名前で残ります。
+func loadState(path string) (*appState, error) {
+	state := &appState{path: path}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return state, err
+	}
+	if err := json.Unmarshal(data, state); err != nil {
+		return &appState{path: path, corrupt: true}, err
+	}
+	return state, nil
+}
+
+// save は、状態ファイルに書き込みます。
+func (state *appState) save() error {
+	if err := os.MkdirAll(filepath.Dir(state.path), os.ModePerm); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// 壊れた状態ファイルは、選択履歴や固定した画像を手動で戻せるよう、上書きせずに別の名前で残す
+	if state.corrupt {
+		badPath := state.path + ".bad"
+		if err := os.Rename(state.path, badPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to keep the corrupted state file: %w", err)
+		}
+		slog.Warn("Kept the corrupted state file", "path", badPath)
+		state.corrupt = false
+	}
+
+	// 書き込み途中で中断しても状態ファイルが壊れないよう、一時ファイルに書き込んでから置き換える
+	tmpPath := state.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, state.path)
+}
+
+// recordPick は、画像の選択を履歴に追加します。
+func (state *appState) recordPick(record pickRecord) {
+	state.History = append(state.History, record)
+	if len(state.History) > maxHistoryEntries {
+		state.History = state.History[len(state.History)-maxHistoryEntries:]
+	}
+}
+
+// lastPick は、指定されたスプラッシュスクリーンファイルに対する最後の選択を返します。
+// destination が空の場合は、保存先に関わらず最後の選択を返します。
+func (state *appState) lastPick(destination string) (pickRecord, bool) {
+	for i := len(state.History) - 1; i >= 0; i-- {
+		if destination == "" || fileKey(state.History[i].Destination) == fileKey(destination) {
+			return state.History[i], true
+		}
+	}
+	return pickRecord{}, false
+}
+
+// resolveCurrent は、現在のスプラッシュスクリーンの元の画像の情報を返します。
+// スプラッシュスクリーンファイルに埋め込まれたメタデータを優先し、読み込めない場合は状態ファイルの選択履歴を使用します。
+// destination が空の場合（保存先を取得できなかった場合）は、状態ファイルの最後の選択を返します。
+func resolveCurrent(destination string, state *appState) (splashScreenInfo, error) {
+	if destination != "" {
+		info, err := readSplashScreenInfo(destination)
+		if err == nil {
+			info.Destination = destination
+			info.From = "metadata"
+			return info, nil
+		}
+		if errors.Is(err, os.ErrNotExist) {
+			return splashScreenInfo{}, fmt.Errorf("splash screen %s does not exist", destination)
+		}
+	}
+
+	record, ok := state.lastPick(destination)
+	if !ok {
+		return splashScreenInfo{}, fmt.Errorf("the source of the current splash screen is unknown")
+	}
+	return splashScreenInfo{
+		Source:      record.Source,
+		Destination: record.Destination,
+		PickedAt:    record.PickedAt,
+		From:        "state",
+	}, nil
+}
+
+// printHistory は、選択履歴を新しいものから順に表示します。
+func printHistory(w io.Writer, history []pickRecord) {
+	if len(history) == 0 {
+		fmt.Fprintln(w, "No history")
+		return
+	}
+	for i := len(history) - 1; i >= 0; i-- {
+		record := history[i]
+		fmt.Fprintf(w, "%s  %s -> %s\n", record.PickedAt.Format(time.RFC3339), record.Source, record.Destination)
+	}
+}
+
+// writeJSON は、値をインデント付きの JSON として書き込みます。
+func writeJSON(w io.Writer, v any) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStateSaveAndLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data", "state.json")
+	state, err := loadState(path)
+	if err != nil {
+		t.Fatalf("loadState failed: %v", err)
+	}
+	if len(state.History) != 0 {
+		t.Fatalf("Expected empty history, got %v", state.History)
+	}
+
+	pickedAt := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	state.recordPick(pickRecord{Source: "a.png", Destination: "SplashScreen.png", PickedAt: pickedAt})
+	if err := state.save(); err != nil {
+		t.Fatalf("save failed: %v", err)
+	}
+
+	loaded, err := loadState(path)
+	if err != nil {
+		t.Fatalf("loadState failed: %v", err)
+	}
+	if len(loaded.History) != 1 || loaded.History[0].Source != "a.png" || !loaded.History[0].PickedAt.Equal(pickedAt) {
+		t.Errorf("Unexpected history: %v", loaded.History)
+	}
+}
+
+func TestStateLoadCorrupted(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	if err := os.WriteFile(path, []byte("{broken"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	state, err := loadState(path)
+	if err == nil {
+		t.Error("Expected an error for a corrupted state file")
+	}
+	if state == nil || len(state.History) != 0 {
+		t.Fatalf("Expected an empty state, got %v", state)
+	}
+
+	// Saving the new state keeps the corrupted file instead of overwriting it
+	state.recordPick(pickRecord{Source: "a.png", Destination: "SplashScreen.png", PickedAt: time.Now()})
+	if err := state.save(); err != nil {
+		t.Fatalf("save failed: %v", err)
+	}
+	if data, err := os.ReadFile(path + ".bad"); err != nil || string(data) != "{broken" {
+		t.Errorf("Expected the corrupted file to be kept as state.json.bad, got %q, %v", data, err)
+	}
+	if loaded, err := loadState(path); err != nil || len(loaded.History) != 1 {
+		t.Errorf("Expected the new state to be saved, got %v, %v", loaded, err)
+	}
+	// Later saves do not touch the kept file
+	if err := os.Remove(path + ".bad"); err != nil {
+		t.Fatal(err)
+	}
+	if err := state.save(); err != nil {
+		t.Fatalf("save failed: %v", err)
+	}
+	if _, err := os.Stat(path + ".bad"); !os.IsNotExist(err) {
+		t.Errorf("Expected state.json not to be moved again, got %v", err)
+	}
+}
+
+func TestStateRecordPickLimit(t *testing.T) {
+	state := &appState{}
+	for i := 0; i < maxHistoryEntries+5; i++ {
+		state.recordPick(pickRecord{Source: filepath.Join("dir", strings.Repeat("a", i%3)+".png"), PickedAt: time.Unix(int64(i), 0)})
+	}
+	if len(state.History) != maxHistoryEntries {
+		t.Fatalf("Expected %d entries, got %d", maxHistoryEntries, len(state.History))
+	}
+	if !state.History[0].PickedAt.Equal(time.Unix(5, 0)) {
+		t.Errorf("Expected the oldest entries to be dropped, first is %v", state.History[0].PickedAt)
+	}
+}
+
+func TestResolveCurrent(t *testing.T) {
+	dir := t.TempDir()
+	generated := filepath.Join(dir, "generated.png")
+	srcPath := filepath.Join(dir, "source.png")
+	writeTestPNG(t, srcPath, 16, 9)
+	texts, err := splashScreenTexts(srcPath, time.Now(), false)
+	if err != nil {
+		t.Fatalf("splashScreenTexts failed: %v", err)
+	}
+	if err := resizePNGFile(srcPath, generated, 16, 9, 0, texts...); err != nil {
+		t.Fatalf("resizePNGFile failed: %v", err)
+	}
+	plain := filepath.Join(dir, "plain.png")
+	writeTestPNG(t, plain, 16, 9)
+
+	state := &appState{History: []pickRecord{
+		{Source: "old.png", Destination: plain, PickedAt: time.Unix(100, 0)},
+		{Source: "other.png", Destination: filepath.Join(dir, "other.png"), PickedAt: time.Unix(200, 0)},
+	}}
+
+	// Metadata embedded in the splash screen takes precedence
+	info, err := resolveCurrent(generated, state)
+	if err != nil {
+		t.Fatalf("resolveCurrent failed: %v", err)
+	}
+	abs, _ := filepath.Abs(srcPath)
+	if info.Source != abs || info.From != "metadata" || info.Destination != generated {
+		t.Errorf("Unexpected info from metadata: %+v", info)
+	}
+
+	// Without metadata, the last pick for the same destination is used
+	info, err = resolveCurrent(plain, state)
+	if err != nil {
+		t.Fatalf("resolveCurrent failed: %v", err)
+	}
+	if info.Source != "old.png" || info.From != "state" {
+		t.Errorf("Unexpected info from state: %+v", info)
+	}
+
+	// Without a destination, the last pick is used
+	info, err = resolveCurrent("", state)
+	if err != nil {
+		t.Fatalf("resolveCurrent failed: %v", err)
+	}
+	if info.Source != "other.png" {
+		t.Errorf("Expected the last pick, got %+v", info)
+	}
+
+	if _, err := resolveCurrent(filepath.Join(dir, "missing.png"), state); err == nil {
+		t.Error("Expected an error for a missing splash screen")
+	}
+	if _, err := resolveCurrent(plain, &appState{}); err == nil {
+		t.Error("Expected an error when the source is unknown")
+	}
+}
+
+func TestPrintHistory(t *testing.T) {
+	var buf bytes.Buffer
+	printHistory(&buf, nil)
+	if buf.String() != "No history\n" {
+		t.Errorf("Unexpected output: %q", buf.String())
+	}
+
+	buf.Reset()
+	printHistory(&buf, []pickRecord{
+		{Source: "a.png", Destination: "dest.png", PickedAt: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{Source: "b.png", Destination: "dest.png", PickedAt: time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC)},
+	})
+	expected := "2026-01-02T00:00:00Z  b.png -> dest.png\n2026-01-01T00:00:00Z  a.png -> dest.png\n"
+	if buf.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, buf.String())
+	}
+}
+
+func TestWriteJSONCurrent(t *testing.T) {
+	var buf bytes.Buffer
+	info := splashScreenInfo{Source: "a.png", Destination: "dest.png", PickedAt: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), From: "state"}
+	if err := writeJSON(&buf, info); err != nil {
+		t.Fatalf("writeJSON failed: %v", err)
+	}
+
+	var decoded map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("Invalid JSON: %v", err)
+	}
+	if decoded["source"] != "a.png" || decoded["picked_at"] != "2026-01-01T00:00:00Z" || decoded["from"] != "state" {
+		t.Errorf("Unexpected JSON: %s", buf.String())
+	}
+	if _, ok := decoded["software"]; ok {
+		t.Errorf("Expected empty software to be omitted: %s", buf.String())
+	}
+}
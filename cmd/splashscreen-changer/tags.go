@@ -0,0 +1,249 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// サイドカーファイルの拡張子。画像ファイル名の後ろに付ける（例: photo.png.tags）
+const tagSidecarExtension = ".tags"
+
+// tagFilter は、タグによるソース画像の絞り込み条件です。
+type tagFilter struct {
+	Include     []tagExpr
+	Exclude     []tagExpr
+	FromFolders bool
+}
+
+// tagExpr は、タグの条件式です。すべてのタグを持つ画像に一致します。
+// 設定値では、タグを "+" で連結して記述します（例: sunset+group）。
+type tagExpr []string
+
+// parseTagExpr は、"+" で連結されたタグの条件式を解析します。
+func parseTagExpr(expr string) (tagExpr, error) {
+	var tags tagExpr
+	for _, tag := range strings.Split(expr, "+") {
+		tag = normalizeTag(tag)
+		if tag == "" {
+			return nil, fmt.Errorf("invalid tag expression '%s'", expr)
+		}
+		tags = append(tags, tag)
+	}
+	return tags, nil
+}
+
+// matches は、タグの集合が条件式のすべてのタグを含む場合に true を返します。
+func (e tagExpr) matches(tags map[string]bool) bool {
+	for _, tag := range e {
+		if !tags[tag] {
+			return false
+		}
+	}
+	return true
+}
+
+// newTagFilter は、設定値からタグの絞り込み条件を作成します。
+func newTagFilter(config *Config) (tagFilter, error) {
+	filter := tagFilter{FromFolders: config.Tags.FromFolders}
+	for _, expr := range config.Tags.Include {
+		parsed, err := parseTagExpr(expr)
+		if err != nil {
+			return tagFilter{}, err
+		}
+		filter.Include = append(filter.Include, parsed)
+	}
+	for _, expr := range config.Tags.Exclude {
+		parsed, err := parseTagExpr(expr)
+		if err != nil {
+			return tagFilter{}, err
+		}
+		filter.Exclude = append(filter.Exclude, parsed)
+	}
+	return filter, nil
+}
+
+// isEmpty は、絞り込み条件が 1 つも設定されていない場合に true を返します。
+func (f tagFilter) isEmpty() bool {
+	return len(f.Include) == 0 && len(f.Exclude) == 0
+}
+
+// matches は、タグの集合が絞り込み条件を満たす場合に true を返します。
+// Include が空でない場合はいずれかの条件式に一致する必要があり、Exclude のいずれかの条件式に一致する場合は対象外とします。
+func (f tagFilter) matches(tags map[string]bool) bool {
+	for _, expr := range f.Exclude {
+		if expr.matches(tags) {
+			return false
+		}
+	}
+	if len(f.Include) == 0 {
+		return true
+	}
+	for _, expr := range f.Include {
+		if expr.matches(tags) {
+			return true
+		}
+	}
+	return false
+}
+
+// filterByTags は、タグの絞り込み条件を満たす候補のみを返します。
+func filterByTags(candidates []candidate, sources []sourceSpec, filter tagFilter, index *libraryIndex) []candidate {
+	if filter.isEmpty() {
+		return candidates
+	}
+
+	var matched []candidate
+	for _, c := range candidates {
+		tags := map[string]bool{}
+		for _, tag := range collectTags(c, sources, filter.FromFolders, index) {
+			tags[tag] = true
+		}
+		if filter.matches(tags) {
+			matched = append(matched, c)
+		}
+	}
+	return matched
+}
+
+// collectTags は、画像のタグを返します。
+// タグは、インデックスに保存されたタグ、サイドカーファイルのタグ、fromFolders が true の場合はフォルダ名から取得します。
+func collectTags(c candidate, sources []sourceSpec, fromFolders bool, index *libraryIndex) []string {
+	tags := slices.Clone(index.tags(c.Path))
+
+	sidecarTags, err := readSidecarTags(c.Path)
+	if err != nil {
+		slog.Warn("Failed to read tags", "path", c.Path, "error", err)
+	}
+	tags = append(tags, sidecarTags...)
+
+	if fromFolders {
+		tags = append(tags, folderTags(sources[c.Source].Path, c.Path)...)
+	}
+	return tags
+}
+
+// readSidecarTags は、画像のサイドカーファイル（画像ファイル名 + ".tags"）からタグを読み込みます。
+// タグは 1 行に 1 つ、またはカンマ区切りで記述します。"#" で始まる行はコメントとして無視します。
+// サイドカーファイルが存在しない場合は、空のリストを返します。
+func readSidecarTags(imagePath string) ([]string, error) {
+	data, err := os.ReadFile(imagePath + tagSidecarExtension)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var tags []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "#") {
+			continue
+		}
+		for _, tag := range strings.Split(line, ",") {
+			if tag = normalizeTag(tag); tag != "" {
+				tags = append(tags, tag)
+			}
+		}
+	}
+	return tags, nil
+}
+
+// folderTags は、ソースフォルダから画像までの各フォルダ名をタグとして返します。
+// 例えば、ソースフォルダ "Pictures" の画像 "Pictures/events/2026/a.png" のタグは "events" と "2026" になります。
+func folderTags(root, path string) []string {
+	rel, err := filepath.Rel(root, filepath.Dir(path))
+	if err != nil || rel == "." {
+		return nil
+	}
+
+	var tags []string
+	for _, name := range strings.Split(filepath.ToSlash(rel), "/") {
+		if tag := normalizeTag(name); tag != "" && tag != ".." {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// normalizeTag は、タグの前後の空白を取り除き、小文字に変換します。
+func normalizeTag(tag string) string {
+	return strings.ToLower(strings.TrimSpace(tag))
+}
+
+// tags は、インデックスに保存された画像のタグを返します。
+func (index *libraryIndex) tags(path string) []string {
+	return index.Tags[fileKey(path)]
+}
+
+// addTags は、画像にタグを追加し、インデックスに保存します。既に付いているタグは無視します。
+func (index *libraryIndex) addTags(path string, tags ...string) {
+	key := fileKey(path)
+	current := index.Tags[key]
+	for _, tag := range tags {
+		if tag = normalizeTag(tag); tag != "" && !slices.Contains(current, tag) {
+			current = append(current, tag)
+		}
+	}
+	sort.Strings(current)
+	index.Tags[key] = current
+	index.dirty = true
+}
+
+// removeTags は、画像からタグを削除します。タグがなくなった画像はインデックスから削除します。
+func (index *libraryIndex) removeTags(path string, tags ...string) {
+	key := fileKey(path)
+	current := slices.DeleteFunc(slices.Clone(index.Tags[key]), func(tag string) bool {
+		return slices.ContainsFunc(tags, func(removed string) bool { return normalizeTag(removed) == tag })
+	})
+	if len(current) == 0 {
+		delete(index.Tags, key)
+	} else {
+		index.Tags[key] = current
+	}
+	index.dirty = true
+}
+
+// runTagCommand は、tag コマンドで指定されたタグの操作を実行します。
+// - action: add（タグを追加）、remove（タグを削除）、list（タグを表示）のいずれか
+// - args: 画像ファイルのパスと、タグのリスト。list の場合は画像ファイルのパスのみ（省略するとタグが付いたすべての画像を表示）
+func runTagCommand(action string, args []string, index *libraryIndex, w io.Writer) error {
+	switch action {
+	case "add", "remove":
+		if len(args) < 2 {
+			return fmt.Errorf("usage: tag %s <image path> <tag>...", action)
+		}
+		path := args[0]
+		if action == "add" {
+			if _, err := os.Stat(path); err != nil {
+				return fmt.Errorf("image '%s' does not exist", path)
+			}
+			index.addTags(path, args[1:]...)
+		} else {
+			index.removeTags(path, args[1:]...)
+		}
+		fmt.Fprintf(w, "%s: %s\n", path, strings.Join(index.tags(path), ", "))
+		return nil
+	case "list":
+		if len(args) > 0 {
+			fmt.Fprintf(w, "%s: %s\n", args[0], strings.Join(index.tags(args[0]), ", "))
+			return nil
+		}
+		paths := make([]string, 0, len(index.Tags))
+		for path := range index.Tags {
+			paths = append(paths, path)
+		}
+		sort.Strings(paths)
+		for _, path := range paths {
+			fmt.Fprintf(w, "%s: %s\n", path, strings.Join(index.Tags[path], ", "))
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown tag action '%s' (expected add, remove or list)", action)
+}
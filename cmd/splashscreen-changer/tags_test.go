@@ -0,0 +1,162 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTagFilterMatches(t *testing.T) {
+	var config Config
+	config.Tags.Include = []string{"sunset+group", "Event-2026"}
+	config.Tags.Exclude = []string{"private"}
+	filter, err := newTagFilter(&config)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	tests := []struct {
+		name string
+		tags []string
+		want bool
+	}{
+		{"All tags of an expression", []string{"sunset", "group"}, true},
+		{"Part of an expression", []string{"sunset"}, false},
+		{"Other expression", []string{"event-2026"}, true},
+		{"Excluded", []string{"event-2026", "private"}, false},
+		{"No tags", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tags := map[string]bool{}
+			for _, tag := range tt.tags {
+				tags[tag] = true
+			}
+			if got := filter.matches(tags); got != tt.want {
+				t.Errorf("matches(%v) = %v, want %v", tt.tags, got, tt.want)
+			}
+		})
+	}
+
+	// Without include expressions, everything not excluded matches
+	excludeOnly := tagFilter{Exclude: []tagExpr{{"private"}}}
+	if !excludeOnly.matches(map[string]bool{}) {
+		t.Errorf("Expected untagged image to match an exclude-only filter")
+	}
+
+	config.Tags.Include = []string{"sunset+"}
+	if _, err := newTagFilter(&config); err == nil {
+		t.Errorf("Expected an error for an invalid expression, got nil")
+	}
+}
+
+func TestReadSidecarTags(t *testing.T) {
+	imagePath := filepath.Join(t.TempDir(), "photo.png")
+	tags, err := readSidecarTags(imagePath)
+	if err != nil || tags != nil {
+		t.Fatalf("Expected no tags without a sidecar file, got %v, %v", tags, err)
+	}
+
+	os.WriteFile(imagePath+tagSidecarExtension, []byte("# comment\nSunset, group\n\nevent-2026\r\n"), 0644)
+	tags, err = readSidecarTags(imagePath)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if strings.Join(tags, ",") != "sunset,group,event-2026" {
+		t.Errorf("Unexpected tags: %v", tags)
+	}
+}
+
+func TestFolderTags(t *testing.T) {
+	root := filepath.Join("pictures", "vrchat")
+	if got := folderTags(root, filepath.Join(root, "Events", "2026", "a.png")); strings.Join(got, ",") != "events,2026" {
+		t.Errorf("Unexpected tags: %v", got)
+	}
+	if got := folderTags(root, filepath.Join(root, "a.png")); len(got) != 0 {
+		t.Errorf("Expected no tags for an image in the root, got %v", got)
+	}
+}
+
+func TestFilterByTags(t *testing.T) {
+	root := t.TempDir()
+	paths := []string{
+		filepath.Join(root, "sunset", "a.png"),
+		filepath.Join(root, "b.png"),
+		filepath.Join(root, "c.png"),
+	}
+	for _, path := range paths {
+		os.MkdirAll(filepath.Dir(path), os.ModePerm)
+		writeTestPNG(t, path, 4, 4)
+	}
+	os.WriteFile(paths[1]+tagSidecarExtension, []byte("sunset"), 0644)
+
+	index := newLibraryIndex("")
+	index.addTags(paths[2], "Sunset", "private")
+
+	sources := []sourceSpec{{Path: root}}
+	candidates := []candidate{{Path: paths[0]}, {Path: paths[1]}, {Path: paths[2]}}
+	filter := tagFilter{Include: []tagExpr{{"sunset"}}, Exclude: []tagExpr{{"private"}}, FromFolders: true}
+	got := filterByTags(candidates, sources, filter, index)
+	if len(got) != 2 || got[0].Path != paths[0] || got[1].Path != paths[1] {
+		t.Errorf("Unexpected candidates: %v", got)
+	}
+
+	// Folder names are ignored unless enabled
+	filter.FromFolders = false
+	got = filterByTags(candidates, sources, filter, index)
+	if len(got) != 1 || got[0].Path != paths[1] {
+		t.Errorf("Unexpected candidates: %v", got)
+	}
+}
+
+func TestRunTagCommand(t *testing.T) {
+	tmpDir := t.TempDir()
+	imagePath := filepath.Join(tmpDir, "photo.png")
+	writeTestPNG(t, imagePath, 4, 4)
+	indexPath := filepath.Join(tmpDir, "library.json")
+	index, _ := loadLibraryIndex(indexPath)
+
+	var buf bytes.Buffer
+	if err := runTagCommand("add", []string{imagePath, "sunset", "Group", "sunset"}, index, &buf); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !strings.Contains(buf.String(), "group, sunset") {
+		t.Errorf("Unexpected output: %s", buf.String())
+	}
+	index.save()
+
+	// Tags survive a reload and a rebuild of the index
+	index, _ = loadLibraryIndex(indexPath)
+	index.reset()
+	if got := index.tags(imagePath); len(got) != 2 {
+		t.Fatalf("Expected 2 tags, got %v", got)
+	}
+
+	buf.Reset()
+	if err := runTagCommand("remove", []string{imagePath, "SUNSET"}, index, &buf); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if got := index.tags(imagePath); len(got) != 1 || got[0] != "group" {
+		t.Errorf("Expected only 'group' to remain, got %v", got)
+	}
+
+	buf.Reset()
+	if err := runTagCommand("list", nil, index, &buf); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !strings.Contains(buf.String(), ": group") {
+		t.Errorf("Unexpected output: %s", buf.String())
+	}
+
+	if err := runTagCommand("add", []string{filepath.Join(tmpDir, "missing.png"), "x"}, index, &buf); err == nil {
+		t.Errorf("Expected an error for a missing image, got nil")
+	}
+	if err := runTagCommand("add", []string{imagePath}, index, &buf); err == nil {
+		t.Errorf("Expected an error without tags, got nil")
+	}
+	if err := runTagCommand("rename", nil, index, &buf); err == nil {
+		t.Errorf("Expected an error for an unknown action, got nil")
+	}
+}
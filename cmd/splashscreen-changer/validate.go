@@ -0,0 +1,371 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// configProblem は、設定値の問題です。
+type configProblem struct {
+	// Field は、問題のある項目のキー（例: destination.width、source.paths[0].path）。ファイル全体の問題の場合は空
+	Field string `json:"field,omitempty"`
+	// Line・Column は、設定ファイルでの位置。設定ファイルで指定されていない項目の場合は 0
+	Line   int `json:"line,omitempty"`
+	Column int `json:"column,omitempty"`
+	// Env は、環境変数で指定された項目の場合の環境変数名
+	Env string `json:"env,omitempty"`
+	// Flag は、コマンドライン引数で指定された項目の場合の引数名（例: --destination.width）
+	Flag string `json:"flag,omitempty"`
+	// File は、問題のある項目を指定した設定ファイルのパス。インクルードしたファイルなど、読み込んだ設定ファイル以外の場合に使用する
+	File    string `json:"file,omitempty"`
+	Message string `json:"message"`
+	// Suggestion は、不明なキーの場合に、名前が近い設定項目のキー
+	Suggestion string `json:"suggestion,omitempty"`
+	// err は、元のエラー。終了コードの判別に使用する
+	err error
+}
+
+// newConfigProblem は、項目のエラーから問題を作成します。
+func newConfigProblem(field string, err error) configProblem {
+	return configProblem{Field: field, Message: err.Error(), err: err}
+}
+
+// location は、問題の位置を "config.yml:5:3" や "SOURCE_PATH (environment variable)"、"--source.path (command-line flag)" の形式で返します。位置がない場合は空文字列を返します。
+func (p configProblem) location(path string) string {
+	if p.File != "" {
+		path = p.File
+	}
+	switch {
+	case p.Flag != "":
+		return p.Flag + " (command-line flag)"
+	case p.Env != "":
+		return p.Env + " (environment variable)"
+	case p.Line > 0 && p.Column > 0 && path != "":
+		return fmt.Sprintf("%s:%d:%d", path, p.Line, p.Column)
+	case p.Line > 0 && path != "":
+		return fmt.Sprintf("%s:%d", path, p.Line)
+	case p.Line > 0 && p.Column > 0:
+		return fmt.Sprintf("line %d, column %d", p.Line, p.Column)
+	case p.Line > 0:
+		return fmt.Sprintf("line %d", p.Line)
+	}
+	return ""
+}
+
+// format は、問題を位置付きのメッセージにします。
+func (p configProblem) format(path string) string {
+	if location := p.location(path); location != "" {
+		return location + ": " + p.Message
+	}
+	return p.Message
+}
+
+// configError は、設定値のすべての問題をまとめたエラーです。
+type configError struct {
+	// Path は、設定ファイルのパス
+	Path     string
+	Problems []configProblem
+}
+
+// newConfigError は、問題がある場合に configError を返します。問題がない場合は nil を返します。
+func newConfigError(path string, problems []configProblem) error {
+	if len(problems) == 0 {
+		return nil
+	}
+	return &configError{Path: path, Problems: problems}
+}
+
+func (e *configError) Error() string {
+	if len(e.Problems) == 1 {
+		return e.Problems[0].format(e.Path)
+	}
+	if e.Path == "" {
+		return fmt.Sprintf("found %d problems in the configuration", len(e.Problems))
+	}
+	return fmt.Sprintf("found %d problems in %s", len(e.Problems), e.Path)
+}
+
+// Unwrap は、問題が 1 つの場合に元のエラーを返します。
+// ソースフォルダが存在しないなどの終了コードは、問題が 1 つの場合のみ使用します。
+func (e *configError) Unwrap() error {
+	if len(e.Problems) == 1 {
+		return e.Problems[0].err
+	}
+	return nil
+}
+
+// printConfigProblems は、設定値のすべての問題を表示します。
+func printConfigProblems(w io.Writer, err *configError) {
+	name := err.Path
+	if name == "" {
+		name = "the configuration"
+	}
+	if len(err.Problems) == 1 {
+		fmt.Fprintf(w, "Found 1 problem in %s:\n", name)
+	} else {
+		fmt.Fprintf(w, "Found %d problems in %s:\n", len(err.Problems), name)
+	}
+	for _, problem := range err.Problems {
+		fmt.Fprintf(w, "  %s\n", problem.format(err.Path))
+	}
+}
+
+// yamlPositions は、設定ファイルの各項目のキーと、その位置のノードの対応を返します。
+// キーは "source.paths[0].path" のように、マッピングのキーをピリオドで、シーケンスの要素を [n] で連結したものです。
+func yamlPositions(root *yaml.Node) map[string]*yaml.Node {
+	positions := map[string]*yaml.Node{}
+	walkYAMLKeys(root, "", func(key string, node *yaml.Node) {
+		positions[key] = node
+	})
+	return positions
+}
+
+// walkYAMLKeys は、ノードの中の各項目について、キーとその位置のノード（マッピングのキー、シーケンスの要素）で fn を呼び出します。
+// prefix は、node 自体のキーです。
+func walkYAMLKeys(node *yaml.Node, prefix string, fn func(key string, node *yaml.Node)) {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		for _, child := range node.Content {
+			walkYAMLKeys(child, prefix, fn)
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := node.Content[i].Value
+			if prefix != "" {
+				key = prefix + "." + key
+			}
+			fn(key, node.Content[i])
+			walkYAMLKeys(node.Content[i+1], key, fn)
+		}
+	case yaml.SequenceNode:
+		for i, child := range node.Content {
+			key := prefix + "[" + strconv.Itoa(i) + "]"
+			fn(key, child)
+			walkYAMLKeys(child, key, fn)
+		}
+	}
+}
+
+// locateProblems は、各問題に設定ファイルでの位置、またはコマンドライン引数名・環境変数名を設定します。
+// 項目自体の位置がない場合は、親の項目の位置を使用します（例: source.paths[0].path → source.paths[0]）。
+// 行番号のみがわかる問題（YAML の型のエラー）には、その行の項目のキーを設定します。
+// origins は、項目のキーと、その項目を指定した設定ファイルのパスの対応です（mergeConfigLayers を参照）。
+func locateProblems(problems []configProblem, positions map[string]*yaml.Node, flagFields, envFields explicitFields, origins map[string]string) {
+	for i := range problems {
+		problem := &problems[i]
+		if problem.Field == "" && problem.Line > 0 {
+			for key, node := range positions {
+				if node.Line == problem.Line && len(key) > len(problem.Field) {
+					problem.Field, problem.Column = key, node.Column
+				}
+			}
+			continue
+		}
+		if problem.Line > 0 || problem.Env != "" || problem.Flag != "" || problem.Field == "" {
+			continue
+		}
+		if key := flagFieldKey(flagFields, problem.Field); key != "" {
+			problem.Flag = "--" + key
+			continue
+		}
+		if envFields[problem.Field] {
+			problem.Env = strings.ToUpper(strings.ReplaceAll(problem.Field, ".", "_"))
+			continue
+		}
+		for key := problem.Field; key != ""; key = parentFieldKey(key) {
+			if node, ok := positions[key]; ok {
+				problem.Line, problem.Column = node.Line, node.Column
+				break
+			}
+		}
+	}
+
+	// 設定ファイルでの位置がわかる問題には、その項目を指定した設定ファイルを設定する
+	for i := range problems {
+		if problem := &problems[i]; problem.File == "" && problem.Line > 0 {
+			problem.File = lookupOrigin(origins, problem.Field)
+		}
+	}
+
+	// 設定ファイルごとに、設定ファイルでの位置の順に並べ、位置のない問題は最後にする
+	sort.SliceStable(problems, func(i, j int) bool {
+		a, b := problems[i], problems[j]
+		if (a.Line == 0) != (b.Line == 0) {
+			return a.Line != 0
+		}
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		return a.Line < b.Line
+	})
+}
+
+// lookupOrigin は、項目を指定した設定ファイルのパスを返します。項目自体がない場合は、親の項目のものを返します。
+func lookupOrigin(origins map[string]string, key string) string {
+	for ; key != ""; key = parentFieldKey(key) {
+		if origin, ok := origins[key]; ok {
+			return origin
+		}
+	}
+	return ""
+}
+
+// flagFieldKey は、設定項目 key またはその親の項目のうち、コマンドライン引数で指定された項目のキーを返します。
+// source.paths[0].path のように、--source.paths で指定したリストの項目の問題を、その引数の問題として表示するために使用します。
+func flagFieldKey(flagFields explicitFields, key string) string {
+	for ; key != ""; key = parentFieldKey(key) {
+		if flagFields[key] {
+			return key
+		}
+	}
+	return ""
+}
+
+// parentFieldKey は、親の項目のキーを返します（例: source.paths[0].path → source.paths[0] → source.paths → source）。
+func parentFieldKey(key string) string {
+	if strings.HasSuffix(key, "]") {
+		return key[:strings.LastIndex(key, "[")]
+	}
+	if i := strings.LastIndex(key, "."); i >= 0 {
+		return key[:i]
+	}
+	return ""
+}
+
+// unknownKeyProblems は、設定ファイルのキーのうち、設定項目にないものを問題として返します。
+func unknownKeyProblems(root *yaml.Node, configType reflect.Type) []configProblem {
+	var problems []configProblem
+	var walk func(node *yaml.Node, t reflect.Type, prefix string)
+	walk = func(node *yaml.Node, t reflect.Type, prefix string) {
+		for t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+		switch {
+		case node.Kind == yaml.DocumentNode:
+			for _, child := range node.Content {
+				walk(child, t, prefix)
+			}
+		case node.Kind == yaml.MappingNode && t.Kind() == reflect.Struct:
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				keyNode := node.Content[i]
+				key := keyNode.Value
+				if prefix != "" {
+					key = prefix + "." + key
+				}
+				field, ok := structFieldByYAMLName(t, keyNode.Value)
+				if !ok {
+					problem := configProblem{
+						Field:   key,
+						Line:    keyNode.Line,
+						Column:  keyNode.Column,
+						Message: fmt.Sprintf("unknown key '%s'", key),
+					}
+					if suggestion := suggestYAMLName(t, keyNode.Value); suggestion != "" {
+						if prefix != "" {
+							suggestion = prefix + "." + suggestion
+						}
+						problem.Suggestion = suggestion
+						problem.Message += fmt.Sprintf(", did you mean '%s'?", suggestion)
+					}
+					problems = append(problems, problem)
+					continue
+				}
+				walk(node.Content[i+1], field.Type, key)
+			}
+		case node.Kind == yaml.SequenceNode && t.Kind() == reflect.Slice:
+			for i, child := range node.Content {
+				walk(child, t.Elem(), prefix+"["+strconv.Itoa(i)+"]")
+			}
+		}
+	}
+	walk(root, configType, "")
+	return problems
+}
+
+// structFieldByYAMLName は、yaml タグの名前から構造体のフィールドを探します。
+func structFieldByYAMLName(t reflect.Type, name string) (reflect.StructField, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		if getYAMLName(t.Field(i)) == name {
+			return t.Field(i), true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+// suggestYAMLName は、構造体のフィールドの yaml タグの名前のうち、name に最も近いものを返します。
+// 近い名前がない場合は空文字列を返します。名前の長さの 3 分の 1（最低 1 文字、最大 3 文字）までの違いを近い名前とします。
+func suggestYAMLName(t reflect.Type, name string) string {
+	name = strings.ToLower(name)
+	maxDistance := min(max(len(name)/3, 1), 3)
+	best, bestDistance := "", maxDistance+1
+	for i := 0; i < t.NumField(); i++ {
+		candidate := getYAMLName(t.Field(i))
+		if distance := editDistance(name, candidate); distance < bestDistance {
+			best, bestDistance = candidate, distance
+		}
+	}
+	return best
+}
+
+// editDistance は、2 つの文字列のレーベンシュタイン距離（挿入・削除・置換の回数）を返します。
+// 隣り合う 2 文字の入れ替え（例: recrusive）も 1 回と数えます。
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	// d[i][j] は、ra[:i] と rb[:j] の距離
+	d := make([][]int, len(ra)+1)
+	for i := range d {
+		d[i] = make([]int, len(rb)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+	return d[len(ra)][len(rb)]
+}
+
+// yaml パッケージのエラーメッセージに含まれる行番号（例: "yaml: line 5: ..."、"line 5: cannot unmarshal ..."）
+var yamlLinePattern = regexp.MustCompile(`^(?:yaml: )?line (\d+): (.*)$`)
+
+// yamlErrorProblems は、YAML の構文エラーや型のエラーを、行番号付きの問題に変換します。
+// format は、構文エラーのメッセージに使用する設定ファイルの形式です（JSON も YAML として解析するため）。
+func yamlErrorProblems(err error, format configFormat) []configProblem {
+	messages := []string{err.Error()}
+	var typeErr *yaml.TypeError
+	if errors.As(err, &typeErr) {
+		messages = typeErr.Errors
+	}
+
+	var problems []configProblem
+	for _, message := range messages {
+		problem := configProblem{Message: strings.TrimPrefix(message, "yaml: "), err: err}
+		if m := yamlLinePattern.FindStringSubmatch(message); m != nil {
+			problem.Line, _ = strconv.Atoi(m[1])
+			problem.Message = m[2]
+		}
+		if typeErr == nil {
+			problem.Message = "invalid " + string(format) + ": " + problem.Message
+		}
+		problems = append(problems, problem)
+	}
+	return problems
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// loadConfigProblems loads the config and returns all reported problems.
+func loadConfigProblems(t *testing.T, path string) []configProblem {
+	t.Helper()
+	_, err := LoadConfig(path)
+	var configErr *configError
+	if !errors.As(err, &configErr) {
+		t.Fatalf("Expected a configError, got %v", err)
+	}
+	return configErr.Problems
+}
+
+func TestLoadConfigReportsAllProblems(t *testing.T) {
+	path := writeExplicitConfig(t, "  orientation: diagonal\n  paths:\n    - path: \"\"\n      weight: -1\n",
+		"  width: 0\n  height: -5\nlog:\n  level: verbose\n")
+
+	problems := loadConfigProblems(t, path)
+	want := []struct {
+		field  string
+		line   int
+		column int
+	}{
+		{"source.orientation", 3, 3},
+		{"source.paths[0].path", 5, 7},
+		{"source.paths[0].weight", 6, 7},
+		{"destination.width", 9, 3},
+		{"destination.height", 10, 3},
+		{"log.level", 12, 3},
+	}
+	if len(problems) != len(want) {
+		t.Fatalf("Expected %d problems, got %d: %+v", len(want), len(problems), problems)
+	}
+	for i, w := range want {
+		p := problems[i]
+		if p.Field != w.field || p.Line != w.line || p.Column != w.column {
+			t.Errorf("Problem %d: expected %s at %d:%d, got %s at %d:%d (%s)", i, w.field, w.line, w.column, p.Field, p.Line, p.Column, p.Message)
+		}
+	}
+}
+
+func TestLoadConfigUnknownKey(t *testing.T) {
+	path := writeExplicitConfig(t, "  recusive: true\n", "destinaton:\n  width: 1280\n")
+
+	// Unknown keys are warnings by default
+	loaded, err := loadConfig(path, false, nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	config, warnings := loaded.Config, loaded.Warnings
+	if config.Source.Recursive != true {
+		t.Errorf("Expected the default recursive value, got %t", config.Source.Recursive)
+	}
+	want := []struct {
+		field      string
+		line       int
+		suggestion string
+	}{
+		{"source.recusive", 3, "source.recursive"},
+		{"destinaton", 6, "destination"},
+	}
+	if len(warnings) != len(want) {
+		t.Fatalf("Expected %d warnings, got %+v", len(want), warnings)
+	}
+	for i, w := range want {
+		p := warnings[i]
+		if p.Field != w.field || p.Line != w.line || p.Suggestion != w.suggestion {
+			t.Errorf("Warning %d: expected %s on line %d suggesting %s, got %+v", i, w.field, w.line, w.suggestion, p)
+		}
+		if !strings.Contains(p.Message, "did you mean '"+w.suggestion+"'?") {
+			t.Errorf("Expected a suggestion in the message, got %q", p.Message)
+		}
+	}
+
+	// Unknown keys are errors in strict mode
+	loaded, err = loadConfig(path, true, nil)
+	var configErr *configError
+	if !errors.As(err, &configErr) || len(configErr.Problems) != 2 || len(loaded.Warnings) != 0 {
+		t.Errorf("Expected 2 problems in strict mode, got %v (warnings: %+v)", err, loaded.Warnings)
+	}
+}
+
+func TestSuggestYAMLName(t *testing.T) {
+	sourceType := reflect.TypeOf(Config{}.Source)
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"recusive", "recursive"},
+		{"recrusive", "recursive"},
+		{"Orientation", "orientation"},
+		{"max_pixel", "max_pixels"},
+		{"pth", "path"},
+		{"colour", ""},
+	}
+	for _, tt := range tests {
+		if got := suggestYAMLName(sourceType, tt.name); got != tt.want {
+			t.Errorf("suggestYAMLName(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRunConfigValidateStrict(t *testing.T) {
+	path := writeExplicitConfig(t, "  recusive: true\n", "")
+
+	var out bytes.Buffer
+	if err := runCLI([]string{"config", "validate", "-config", path}, &out); err != nil {
+		t.Fatalf("Expected unknown keys to be warnings, got %v", err)
+	}
+	if !strings.Contains(out.String(), "valid with 1 warning") {
+		t.Errorf("Expected the warning count in the output:\n%s", out.String())
+	}
+
+	out.Reset()
+	t.Setenv("CONFIG_STRICT", "true")
+	err := runCLI([]string{"config", "validate", "-config", path}, &out)
+	if code := exitCodeOf(err); code != exitConfigError {
+		t.Errorf("Expected exit code %d in strict mode, got %d (%v)", exitConfigError, code, err)
+	}
+	if !strings.Contains(out.String(), "did you mean 'source.recursive'?") {
+		t.Errorf("Expected the suggestion in the output:\n%s", out.String())
+	}
+}
+
+func TestLoadConfigTypeError(t *testing.T) {
+	// The type error does not hide the problems of other fields
+	path := writeExplicitConfig(t, "", "  width: wide\n  height: -1\n")
+	problems := loadConfigProblems(t, path)
+	if len(problems) != 2 {
+		t.Fatalf("Expected 2 problems, got %+v", problems)
+	}
+	if problems[0].Field != "destination.width" || problems[0].Line != 5 {
+		t.Errorf("Expected the type error at destination.width on line 5, got %+v", problems[0])
+	}
+	if problems[1].Field != "destination.height" || problems[1].Line != 6 {
+		t.Errorf("Expected the height problem on line 6, got %+v", problems[1])
+	}
+}
+
+func TestLoadConfigSyntaxError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yml")
+	if err := os.WriteFile(path, []byte("source:\n  path: [\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	problems := loadConfigProblems(t, path)
+	if len(problems) != 1 || problems[0].Line == 0 || !strings.HasPrefix(problems[0].Message, "invalid YAML") {
+		t.Errorf("Expected a syntax error with a line number, got %+v", problems)
+	}
+}
+
+func TestLoadConfigEnvProblemLocation(t *testing.T) {
+	path := writeExplicitConfig(t, "", "")
+	t.Setenv("DESTINATION_WIDTH", "-1")
+	t.Setenv("DESTINATION_HEIGHT", "tall")
+
+	problems := loadConfigProblems(t, path)
+	if len(problems) != 2 {
+		t.Fatalf("Expected 2 problems, got %+v", problems)
+	}
+	for _, p := range problems {
+		want := strings.ToUpper(strings.ReplaceAll(p.Field, ".", "_"))
+		if p.Env != want || p.Line != 0 {
+			t.Errorf("Expected the problem of %s to point to %s, got %+v", p.Field, want, p)
+		}
+		if !strings.HasPrefix(p.format(path), want+" (environment variable): ") {
+			t.Errorf("Unexpected message: %s", p.format(path))
+		}
+	}
+}
+
+func TestRunConfigValidateProblems(t *testing.T) {
+	path := writeExplicitConfig(t, "  orientation: diagonal\n", "  width: 0\n")
+
+	var out bytes.Buffer
+	err := runCLI([]string{"config", "validate", "-config", path}, &out)
+	if code := exitCodeOf(err); code != exitConfigError {
+		t.Errorf("Expected exit code %d, got %d (%v)", exitConfigError, code, err)
+	}
+	for _, want := range []string{"Found 2 problems in " + path, path + ":3:3: ", path + ":6:3: "} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("Expected %q in the output:\n%s", want, out.String())
+		}
+	}
+
+	out.Reset()
+	t.Chdir(t.TempDir())
+	runCLI([]string{"run", "-config", path, "-json"}, &out)
+	var result errorResult
+	if err := json.Unmarshal(out.Bytes(), &result); err != nil {
+		t.Fatalf("Expected JSON output, got %q: %v", out.String(), err)
+	}
+	if len(result.Problems) != 2 || result.Problems[0].Field != "source.orientation" || result.Problems[0].Line != 3 {
+		t.Errorf("Unexpected problems in the JSON output: %+v", result.Problems)
+	}
+}
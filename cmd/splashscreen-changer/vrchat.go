@@ -0,0 +1,307 @@
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// VRChat のスクリーンショットのファイル名に含まれる日時の書式
+const vrchatTimeLayout = "2006-01-02_15-04-05.000"
+
+// VRChat のスクリーンショットのファイル名の形式
+var (
+	// 現在の形式: VRChat_2026-01-02_03-04-05.678_1920x1080.png
+	vrchatFilenamePattern = regexp.MustCompile(`(?i)^VRChat_(\d{4}-\d{2}-\d{2}_\d{2}-\d{2}-\d{2}\.\d{3})_(\d+)x(\d+)`)
+	// 以前の形式: VRChat_1920x1080_2026-01-02_03-04-05.678.png
+	vrchatLegacyFilenamePattern = regexp.MustCompile(`(?i)^VRChat_(\d+)x(\d+)_(\d{4}-\d{2}-\d{2}_\d{2}-\d{2}-\d{2}\.\d{3})`)
+)
+
+// screenshotMetadata は、VRChat のスクリーンショットのファイル名とテキストチャンクから取得したメタデータです。
+// 取得できなかった項目は空のままになります。
+type screenshotMetadata struct {
+	TakenAt   time.Time          `json:"taken_at,omitzero"`
+	Width     int                `json:"width,omitempty"`
+	Height    int                `json:"height,omitempty"`
+	WorldID   string             `json:"world_id,omitempty"`
+	WorldName string             `json:"world_name,omitempty"`
+	Author    screenshotPlayer   `json:"author,omitzero"`
+	Players   []screenshotPlayer `json:"players,omitempty"`
+}
+
+// screenshotPlayer は、スクリーンショットの撮影者または写っているプレイヤーです。
+type screenshotPlayer struct {
+	ID   string `json:"id,omitempty"`
+	Name string `json:"name,omitempty"`
+}
+
+// matches は、プレイヤーのユーザー ID または表示名が name と一致する場合に true を返します。大文字小文字は区別しません。
+func (p screenshotPlayer) matches(name string) bool {
+	return (p.ID != "" && strings.EqualFold(p.ID, name)) || (p.Name != "" && strings.EqualFold(p.Name, name))
+}
+
+// readScreenshotMetadata は、画像のファイル名とテキストチャンクからメタデータを取得します。
+// - ファイル名: 撮影日時、解像度
+// - XMP（VRChat が書き込む）: 撮影日時、ワールド、撮影者
+// - Description（VRCX が書き込む JSON）: ワールド、撮影者、同じインスタンスにいたプレイヤー
+// 同じ項目を複数の場所から取得できる場合は、ファイル名、XMP、Description の順に優先します。
+func readScreenshotMetadata(path string) (screenshotMetadata, error) {
+	meta := parseScreenshotFilename(filepath.Base(path))
+
+	header, texts, err := readPNGTextFile(path)
+	if err != nil {
+		return screenshotMetadata{}, err
+	}
+	if meta.Width == 0 || meta.Height == 0 {
+		meta.Width, meta.Height = header.Width, header.Height
+	}
+
+	// VRCX の Description より VRChat の XMP を優先するため、XMP を先に読み込む
+	slices.SortStableFunc(texts, func(a, b pngText) int {
+		return textPriority(a.Keyword) - textPriority(b.Keyword)
+	})
+	for _, text := range texts {
+		switch text.Keyword {
+		case "XML:com.adobe.xmp":
+			if err := parseVRChatXMP(text.Text, &meta); err != nil {
+				slog.Warn("Failed to parse XMP metadata", "path", path, "error", err)
+			}
+		case "Description":
+			parseVRCXDescription(text.Text, &meta)
+		}
+	}
+	return meta, nil
+}
+
+// textPriority は、メタデータを読み込むテキストチャンクの優先順位を返します。値が小さいほど優先します。
+func textPriority(keyword string) int {
+	if keyword == "XML:com.adobe.xmp" {
+		return 0
+	}
+	return 1
+}
+
+// parseScreenshotFilename は、VRChat のスクリーンショットのファイル名から撮影日時と解像度を取得します。
+// ファイル名の日時はローカル時刻として扱います。形式が異なる場合は空のメタデータを返します。
+func parseScreenshotFilename(name string) screenshotMetadata {
+	var timestamp, width, height string
+	if m := vrchatFilenamePattern.FindStringSubmatch(name); m != nil {
+		timestamp, width, height = m[1], m[2], m[3]
+	} else if m := vrchatLegacyFilenamePattern.FindStringSubmatch(name); m != nil {
+		width, height, timestamp = m[1], m[2], m[3]
+	} else {
+		return screenshotMetadata{}
+	}
+
+	var meta screenshotMetadata
+	if t, err := time.ParseInLocation(vrchatTimeLayout, timestamp, time.Local); err == nil {
+		meta.TakenAt = t
+	}
+	w, errW := strconv.Atoi(width)
+	h, errH := strconv.Atoi(height)
+	if errW == nil && errH == nil {
+		meta.Width, meta.Height = w, h
+	}
+	return meta
+}
+
+// parseVRChatXMP は、VRChat が書き込む XMP からワールド・撮影者・撮影日時を取得します。
+// 値は要素としても属性としても記述できるため、名前空間を問わずローカル名で判定します。
+func parseVRChatXMP(data string, meta *screenshotMetadata) error {
+	decoder := xml.NewDecoder(strings.NewReader(data))
+	current := ""
+	for {
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			current = t.Name.Local
+			for _, attr := range t.Attr {
+				setXMPValue(meta, attr.Name.Local, attr.Value)
+			}
+		case xml.CharData:
+			setXMPValue(meta, current, string(t))
+		case xml.EndElement:
+			current = ""
+		}
+	}
+}
+
+// setXMPValue は、XMP の項目の値をメタデータに設定します。既に値がある項目は上書きしません。
+func setXMPValue(meta *screenshotMetadata, name, value string) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return
+	}
+
+	switch name {
+	case "WorldID":
+		setIfEmpty(&meta.WorldID, value)
+	case "WorldDisplayName":
+		setIfEmpty(&meta.WorldName, value)
+	case "Author":
+		setIfEmpty(&meta.Author.Name, value)
+	case "AuthorID":
+		setIfEmpty(&meta.Author.ID, value)
+	case "CreateDate", "DateTimeOriginal":
+		if meta.TakenAt.IsZero() {
+			if t, err := time.Parse(time.RFC3339Nano, value); err == nil {
+				meta.TakenAt = t
+			}
+		}
+	}
+}
+
+// vrcxDescription は、VRCX がスクリーンショットの Description に書き込む JSON です。
+type vrcxDescription struct {
+	Author vrcxUser `json:"author"`
+	World  struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"world"`
+	Players []vrcxUser `json:"players"`
+}
+
+type vrcxUser struct {
+	ID          string `json:"id"`
+	DisplayName string `json:"displayName"`
+}
+
+// parseVRCXDescription は、VRCX が書き込む Description からワールド・撮影者・プレイヤーを取得します。
+// JSON として解析できない場合は何もしません。
+func parseVRCXDescription(data string, meta *screenshotMetadata) {
+	var description vrcxDescription
+	if err := json.Unmarshal([]byte(data), &description); err != nil {
+		return
+	}
+
+	setIfEmpty(&meta.WorldID, description.World.ID)
+	setIfEmpty(&meta.WorldName, description.World.Name)
+	setIfEmpty(&meta.Author.ID, description.Author.ID)
+	setIfEmpty(&meta.Author.Name, description.Author.DisplayName)
+	if len(meta.Players) == 0 {
+		for _, player := range description.Players {
+			meta.Players = append(meta.Players, screenshotPlayer{ID: player.ID, Name: player.DisplayName})
+		}
+	}
+}
+
+// setIfEmpty は、dst が空の場合のみ value を設定します。
+func setIfEmpty(dst *string, value string) {
+	if *dst == "" {
+		*dst = value
+	}
+}
+
+// screenshotFilter は、VRChat のスクリーンショットのメタデータによるソース画像の絞り込み条件です。
+type screenshotFilter struct {
+	Worlds  []string
+	Players []string
+	// MaxAge は、撮影からの経過時間の上限。0 の場合は制限しない
+	MaxAge time.Duration
+	// Now は、経過時間の基準とする時刻
+	Now time.Time
+}
+
+// newScreenshotFilter は、設定値からスクリーンショットの絞り込み条件を作成します。
+func newScreenshotFilter(config *Config, now time.Time) screenshotFilter {
+	return screenshotFilter{
+		Worlds:  config.Screenshot.Worlds,
+		Players: config.Screenshot.Players,
+		MaxAge:  time.Duration(config.Screenshot.MaxAgeDays) * 24 * time.Hour,
+		Now:     now,
+	}
+}
+
+// isEmpty は、絞り込み条件が 1 つも設定されていない場合に true を返します。
+func (f screenshotFilter) isEmpty() bool {
+	return len(f.Worlds) == 0 && len(f.Players) == 0 && f.MaxAge <= 0
+}
+
+// matches は、メタデータが絞り込み条件を満たす場合に true を返します。
+// 撮影日時が取得できない画像は、ファイルの更新日時を撮影日時として扱います。
+func (f screenshotFilter) matches(meta screenshotMetadata, modTime time.Time) bool {
+	if len(f.Worlds) > 0 && !slices.ContainsFunc(f.Worlds, func(world string) bool {
+		return (meta.WorldID != "" && strings.EqualFold(meta.WorldID, world)) ||
+			(meta.WorldName != "" && strings.EqualFold(meta.WorldName, world))
+	}) {
+		return false
+	}
+
+	if len(f.Players) > 0 && !slices.ContainsFunc(f.Players, func(name string) bool {
+		return meta.Author.matches(name) || slices.ContainsFunc(meta.Players, func(p screenshotPlayer) bool { return p.matches(name) })
+	}) {
+		return false
+	}
+
+	if f.MaxAge > 0 {
+		takenAt := meta.TakenAt
+		if takenAt.IsZero() {
+			takenAt = modTime
+		}
+		if takenAt.Before(f.Now.Add(-f.MaxAge)) {
+			return false
+		}
+	}
+	return true
+}
+
+// filterByScreenshot は、スクリーンショットの絞り込み条件を満たす候補のみを返します。
+// メタデータはインデックスに保存し、次回以降は画像を読み込まずに使用します。
+func filterByScreenshot(candidates []candidate, filter screenshotFilter, index *libraryIndex) []candidate {
+	if filter.isEmpty() {
+		return candidates
+	}
+
+	var matched []candidate
+	for _, c := range candidates {
+		entry, err := index.entry(c.Path)
+		if err != nil {
+			slog.Warn("Skipping unreadable image", "path", c.Path, "error", err)
+			continue
+		}
+		meta, err := index.screenshot(c.Path)
+		if err != nil {
+			slog.Warn("Skipping image with unreadable metadata", "path", c.Path, "error", err)
+			continue
+		}
+		if filter.matches(meta, entry.ModTime) {
+			matched = append(matched, c)
+		}
+	}
+	return matched
+}
+
+// screenshot は、指定されたファイルのスクリーンショットのメタデータを返します。
+// インデックスに保存されたメタデータがない場合や古い場合は、画像を読み込んで取得します。
+func (index *libraryIndex) screenshot(path string) (screenshotMetadata, error) {
+	entry, err := index.entry(path)
+	if err != nil {
+		return screenshotMetadata{}, err
+	}
+	if entry.Screenshot != nil {
+		return *entry.Screenshot, nil
+	}
+
+	meta, err := readScreenshotMetadata(path)
+	if err != nil {
+		return screenshotMetadata{}, err
+	}
+	entry.Screenshot = &meta
+	index.dirty = true
+	return meta, nil
+}
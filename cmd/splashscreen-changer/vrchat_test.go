@@ -0,0 +1,201 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParseScreenshotFilename(t *testing.T) {
+	tests := []struct {
+		name          string
+		takenAt       time.Time
+		width, height int
+	}{
+		{"VRChat_2026-01-02_03-04-05.678_1920x1080.png", time.Date(2026, 1, 2, 3, 4, 5, 678000000, time.Local), 1920, 1080},
+		{"VRChat_2026-01-02_03-04-05.678_3840x2160_edited.png", time.Date(2026, 1, 2, 3, 4, 5, 678000000, time.Local), 3840, 2160},
+		{"VRChat_1920x1080_2022-05-06_07-08-09.123.png", time.Date(2022, 5, 6, 7, 8, 9, 123000000, time.Local), 1920, 1080},
+		{"vrchat_2026-01-02_03-04-05.678_1080x1920.PNG", time.Date(2026, 1, 2, 3, 4, 5, 678000000, time.Local), 1080, 1920},
+		{"photo.png", time.Time{}, 0, 0},
+		{"VRChat_2026-13-02_03-04-05.678_1920x1080.png", time.Time{}, 1920, 1080},
+	}
+
+	for _, tt := range tests {
+		meta := parseScreenshotFilename(tt.name)
+		if !meta.TakenAt.Equal(tt.takenAt) {
+			t.Errorf("%s: expected time %v, got %v", tt.name, tt.takenAt, meta.TakenAt)
+		}
+		if meta.Width != tt.width || meta.Height != tt.height {
+			t.Errorf("%s: expected %dx%d, got %dx%d", tt.name, tt.width, tt.height, meta.Width, meta.Height)
+		}
+	}
+}
+
+func TestReadScreenshotMetadataVRChatXMP(t *testing.T) {
+	meta, err := readScreenshotMetadata(filepath.Join("testdata", "VRChat_2026-01-02_03-04-05.678_16x9.png"))
+	if err != nil {
+		t.Fatalf("readScreenshotMetadata failed: %v", err)
+	}
+
+	// The filename takes precedence over XMP for the timestamp
+	if expected := time.Date(2026, 1, 2, 3, 4, 5, 678000000, time.Local); !meta.TakenAt.Equal(expected) {
+		t.Errorf("Expected time %v, got %v", expected, meta.TakenAt)
+	}
+	if meta.Width != 16 || meta.Height != 9 {
+		t.Errorf("Expected 16x9, got %dx%d", meta.Width, meta.Height)
+	}
+	if meta.WorldID != "wrld_4cf554b4-430c-4f8f-b53e-1f294eed230b" || meta.WorldName != "The Black Cat" {
+		t.Errorf("Unexpected world: %s (%s)", meta.WorldName, meta.WorldID)
+	}
+	if meta.Author.Name != "Photographer" || meta.Author.ID != "usr_11111111-1111-1111-1111-111111111111" {
+		t.Errorf("Unexpected author: %+v", meta.Author)
+	}
+	if len(meta.Players) != 0 {
+		t.Errorf("Expected no players, got %v", meta.Players)
+	}
+}
+
+func TestReadScreenshotMetadataVRCX(t *testing.T) {
+	meta, err := readScreenshotMetadata(filepath.Join("testdata", "vrcx_screenshot.png"))
+	if err != nil {
+		t.Fatalf("readScreenshotMetadata failed: %v", err)
+	}
+
+	// Without a VRChat filename, the resolution comes from the image header
+	if !meta.TakenAt.IsZero() {
+		t.Errorf("Expected no timestamp, got %v", meta.TakenAt)
+	}
+	if meta.Width != 16 || meta.Height != 9 {
+		t.Errorf("Expected 16x9, got %dx%d", meta.Width, meta.Height)
+	}
+	if meta.WorldName != "Japan Shrine" || meta.WorldID != "wrld_5e4f1e21-7a8d-4b8c-a0a4-0f3b7d3c8e11" {
+		t.Errorf("Unexpected world: %s (%s)", meta.WorldName, meta.WorldID)
+	}
+	if meta.Author.Name != "Camera Person" {
+		t.Errorf("Unexpected author: %+v", meta.Author)
+	}
+	expected := []screenshotPlayer{
+		{ID: "usr_33333333-3333-3333-3333-333333333333", Name: "Friend A"},
+		{ID: "usr_44444444-4444-4444-4444-444444444444", Name: "Friend B"},
+	}
+	if len(meta.Players) != len(expected) {
+		t.Fatalf("Expected %d players, got %v", len(expected), meta.Players)
+	}
+	for i, player := range expected {
+		if meta.Players[i] != player {
+			t.Errorf("Player %d: expected %+v, got %+v", i, player, meta.Players[i])
+		}
+	}
+}
+
+func TestReadScreenshotMetadataLegacy(t *testing.T) {
+	// Legacy filename, with compressed attribute-style XMP written after the image data
+	meta, err := readScreenshotMetadata(filepath.Join("testdata", "VRChat_16x9_2022-05-06_07-08-09.123.png"))
+	if err != nil {
+		t.Fatalf("readScreenshotMetadata failed: %v", err)
+	}
+
+	if expected := time.Date(2022, 5, 6, 7, 8, 9, 123000000, time.Local); !meta.TakenAt.Equal(expected) {
+		t.Errorf("Expected time %v, got %v", expected, meta.TakenAt)
+	}
+	if meta.WorldID != "wrld_legacy" || meta.WorldName != "Legacy World" {
+		t.Errorf("Unexpected world: %s (%s)", meta.WorldName, meta.WorldID)
+	}
+}
+
+func TestReadScreenshotMetadataXMPDate(t *testing.T) {
+	meta := screenshotMetadata{}
+	xmp := `<x:xmpmeta xmlns:x="adobe:ns:meta/"><xmp:CreateDate xmlns:xmp="http://ns.adobe.com/xap/1.0/">2026-01-02T03:04:05.678+09:00</xmp:CreateDate></x:xmpmeta>`
+	if err := parseVRChatXMP(xmp, &meta); err != nil {
+		t.Fatalf("parseVRChatXMP failed: %v", err)
+	}
+	if expected := time.Date(2026, 1, 1, 18, 4, 5, 678000000, time.UTC); !meta.TakenAt.Equal(expected) {
+		t.Errorf("Expected time %v, got %v", expected, meta.TakenAt)
+	}
+}
+
+func TestScreenshotFilter(t *testing.T) {
+	now := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	meta := screenshotMetadata{
+		TakenAt:   now.Add(-10 * 24 * time.Hour),
+		WorldID:   "wrld_1",
+		WorldName: "Japan Shrine",
+		Author:    screenshotPlayer{ID: "usr_1", Name: "Camera Person"},
+		Players:   []screenshotPlayer{{ID: "usr_2", Name: "Friend A"}},
+	}
+
+	tests := []struct {
+		name     string
+		filter   screenshotFilter
+		meta     screenshotMetadata
+		modTime  time.Time
+		expected bool
+	}{
+		{"world by name", screenshotFilter{Worlds: []string{"japan shrine"}}, meta, time.Time{}, true},
+		{"world by ID", screenshotFilter{Worlds: []string{"wrld_other", "wrld_1"}}, meta, time.Time{}, true},
+		{"other world", screenshotFilter{Worlds: []string{"wrld_other"}}, meta, time.Time{}, false},
+		{"unknown world", screenshotFilter{Worlds: []string{"wrld_1"}}, screenshotMetadata{}, time.Time{}, false},
+		{"player", screenshotFilter{Players: []string{"friend a"}}, meta, time.Time{}, true},
+		{"author as player", screenshotFilter{Players: []string{"usr_1"}}, meta, time.Time{}, true},
+		{"other player", screenshotFilter{Players: []string{"Friend B"}}, meta, time.Time{}, false},
+		{"within max age", screenshotFilter{MaxAge: 30 * 24 * time.Hour, Now: now}, meta, time.Time{}, true},
+		{"older than max age", screenshotFilter{MaxAge: 7 * 24 * time.Hour, Now: now}, meta, time.Time{}, false},
+		{"max age falls back to mod time", screenshotFilter{MaxAge: 7 * 24 * time.Hour, Now: now}, screenshotMetadata{}, now.Add(-time.Hour), true},
+		{"old mod time", screenshotFilter{MaxAge: 7 * 24 * time.Hour, Now: now}, screenshotMetadata{}, now.Add(-8 * 24 * time.Hour), false},
+		{"all conditions", screenshotFilter{Worlds: []string{"wrld_1"}, Players: []string{"usr_2"}, MaxAge: 30 * 24 * time.Hour, Now: now}, meta, time.Time{}, true},
+	}
+
+	for _, tt := range tests {
+		if got := tt.filter.matches(tt.meta, tt.modTime); got != tt.expected {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.expected, got)
+		}
+	}
+}
+
+func TestFilterByScreenshot(t *testing.T) {
+	dir := t.TempDir()
+	var candidates []candidate
+	for _, name := range []string{"VRChat_2026-01-02_03-04-05.678_16x9.png", "vrcx_screenshot.png", "VRChat_16x9_2022-05-06_07-08-09.123.png"} {
+		data, err := os.ReadFile(filepath.Join("testdata", name))
+		if err != nil {
+			t.Fatalf("Failed to read fixture: %v", err)
+		}
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatalf("Failed to write fixture: %v", err)
+		}
+		candidates = append(candidates, candidate{Path: path})
+	}
+
+	index := newLibraryIndex(filepath.Join(t.TempDir(), "library.json"))
+	filter := screenshotFilter{Worlds: []string{"The Black Cat", "Japan Shrine"}}
+	matched := filterByScreenshot(candidates, filter, index)
+	if len(matched) != 2 || matched[0].Path != candidates[0].Path || matched[1].Path != candidates[1].Path {
+		t.Errorf("Expected the first two fixtures, got %v", matched)
+	}
+
+	// The metadata is stored in the index and survives a save and reload
+	if err := index.save(); err != nil {
+		t.Fatalf("Failed to save index: %v", err)
+	}
+	loaded, err := loadLibraryIndex(index.path)
+	if err != nil {
+		t.Fatalf("Failed to load index: %v", err)
+	}
+	entry := loaded.Files[candidates[1].Path]
+	if entry == nil || entry.Screenshot == nil || entry.Screenshot.WorldName != "Japan Shrine" || len(entry.Screenshot.Players) != 2 {
+		t.Errorf("Expected screenshot metadata in the index, got %+v", entry)
+	}
+
+	// Files without a timestamp use their modification time for the age limit
+	filter = screenshotFilter{MaxAge: 30 * 24 * time.Hour, Now: time.Date(2026, 1, 20, 0, 0, 0, 0, time.Local)}
+	old := filter.Now.Add(-60 * 24 * time.Hour)
+	if err := os.Chtimes(candidates[1].Path, old, old); err != nil {
+		t.Fatalf("Failed to change file time: %v", err)
+	}
+	matched = filterByScreenshot(candidates, filter, loaded)
+	if len(matched) != 1 || matched[0].Path != candidates[0].Path {
+		t.Errorf("Expected only the recent screenshot, got %v", matched)
+	}
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// configWizard は、config init コマンドで設定値を対話形式で尋ねるウィザードです。
+// 入力が終わった（EOF）後の質問には、既定値を回答したものとして扱います。
+type configWizard struct {
+	in  *bufio.Reader
+	out io.Writer
+	eof bool
+}
+
+func newConfigWizard(in io.Reader, out io.Writer) *configWizard {
+	return &configWizard{in: bufio.NewReader(in), out: out}
+}
+
+// readLine は、1 行を読み込み、前後の空白を取り除いて返します。
+func (w *configWizard) readLine() string {
+	if w.eof {
+		fmt.Fprintln(w.out)
+		return ""
+	}
+	line, err := w.in.ReadString('\n')
+	if err != nil {
+		w.eof = true
+		fmt.Fprintln(w.out)
+	}
+	return strings.TrimSpace(line)
+}
+
+// ask は、質問を表示して回答を読み込みます。回答が空の場合は defaultValue を返します。
+// apply で回答を設定値に反映し、エラーの場合はエラーを表示して再度尋ねます。
+func (w *configWizard) ask(question, defaultValue string, apply func(answer string) error) {
+	for {
+		if defaultValue != "" {
+			fmt.Fprintf(w.out, "%s [%s]: ", question, defaultValue)
+		} else {
+			fmt.Fprintf(w.out, "%s: ", question)
+		}
+		answer := w.readLine()
+		if answer == "" {
+			answer = defaultValue
+		}
+		err := apply(answer)
+		if err == nil {
+			return
+		}
+		fmt.Fprintf(w.out, "Invalid value: %v\n", err)
+		// 入力が終わっている場合は再度尋ねても回答できないため、既定値のままにする
+		if w.eof {
+			if err := apply(defaultValue); err != nil {
+				fmt.Fprintf(w.out, "Leaving the default value: %v\n", err)
+			}
+			return
+		}
+	}
+}
+
+// askPath は、フォルダのパスを尋ねます。エクスプローラーの「パスのコピー」で付く前後の引用符は取り除きます。
+func (w *configWizard) askPath(question string, value *string, check func() error) {
+	previous := *value
+	w.ask(question, previous, func(answer string) error {
+		*value = strings.Trim(answer, `"'`)
+		if err := check(); err != nil {
+			*value = previous
+			return err
+		}
+		return nil
+	})
+}
+
+// askBool は、はい・いいえで回答する質問をします。
+func (w *configWizard) askBool(question string, value *bool) {
+	defaultValue := "y/N"
+	if *value {
+		defaultValue = "Y/n"
+	}
+	fmt.Fprintf(w.out, "%s (%s): ", question, defaultValue)
+	for {
+		switch answer := strings.ToLower(w.readLine()); answer {
+		case "":
+			return
+		case "y", "yes":
+			*value = true
+			return
+		case "n", "no":
+			*value = false
+			return
+		}
+		if w.eof {
+			return
+		}
+		fmt.Fprintf(w.out, "Please answer y or n (%s): ", defaultValue)
+	}
+}
+
+// askInt は、整数を尋ねます。
+func (w *configWizard) askInt(question string, value *int, check func() error) {
+	previous := *value
+	w.ask(question, strconv.Itoa(previous), func(answer string) error {
+		n, err := strconv.Atoi(answer)
+		if err != nil {
+			return fmt.Errorf("'%s' is not a number", answer)
+		}
+		*value = n
+		if err := check(); err != nil {
+			*value = previous
+			return err
+		}
+		return nil
+	})
+}
+
+// askString は、文字列を尋ねます。
+func (w *configWizard) askString(question string, value *string, check func() error) {
+	previous := *value
+	w.ask(question, previous, func(answer string) error {
+		*value = answer
+		if err := check(); err != nil {
+			*value = previous
+			return err
+		}
+		return nil
+	})
+}
+
+// run は、ソースフォルダ・保存先のフォルダ・画像の大きさ・画像の選択方法を尋ね、config に設定します。
+// config には、自動で検出した値などの既定値を設定しておきます。各回答は checkConfig でチェックします。
+func (w *configWizard) run(config *Config) {
+	check := func() error { return checkConfig(config) }
+
+	fmt.Fprintln(w.out, "This wizard creates a configuration file. Press Enter to accept the value in brackets.")
+	fmt.Fprintln(w.out)
+
+	fmt.Fprintln(w.out, "Source: the folder containing the images to use as the splash screen.")
+	fmt.Fprintln(w.out, "Leave it empty to use the VRChat folder in your Pictures folder.")
+	w.askPath("Source folder", &config.Source.Path, check)
+	w.askBool("Search subfolders too?", &config.Source.Recursive)
+	fmt.Fprintln(w.out)
+
+	fmt.Fprintln(w.out, "Destination: the VRChat installation folder containing the EasyAntiCheat folder.")
+	fmt.Fprintln(w.out, "Leave it empty to find it from your Steam library folders.")
+	w.askPath("Destination folder", &config.Destination.Path, check)
+	w.askInt("Splash screen width", &config.Destination.Width, check)
+	w.askInt("Splash screen height", &config.Destination.Height, check)
+	fmt.Fprintln(w.out)
+
+	fmt.Fprintln(w.out, "Selection: how images are picked.")
+	w.askString("Orientation of images to pick (landscape, portrait or any)", &config.Source.Orientation, check)
+	w.askBool("Treat near-duplicate images (such as burst shots) as a single image?", &config.Selection.Deduplicate)
+	fmt.Fprintln(w.out)
+}
+
+// wizardFields は、config init コマンドで設定ファイルに書き込む項目です（セクション名・項目名）。
+var wizardFields = [][2]string{
+	{"source", "path"},
+	{"source", "recursive"},
+	{"source", "orientation"},
+	{"destination", "path"},
+	{"destination", "width"},
+	{"destination", "height"},
+	{"selection", "deduplicate"},
+}
+
+// marshalCommentedConfig は、wizardFields の項目を、各項目の説明（help タグ）をコメントとして付けた YAML に変換します。
+// 空の文字列の項目は書き込みません。
+func marshalCommentedConfig(config *Config) ([]byte, error) {
+	root := &yaml.Node{Kind: yaml.MappingNode}
+	sections := map[string]*yaml.Node{}
+	for _, name := range wizardFields {
+		value, help, err := lookupConfigField(config, name[0]+"."+name[1])
+		if err != nil {
+			return nil, err
+		}
+		if value.Kind() == reflect.String && value.String() == "" {
+			continue
+		}
+
+		section, ok := sections[name[0]]
+		if !ok {
+			section = &yaml.Node{Kind: yaml.MappingNode}
+			sections[name[0]] = section
+			root.Content = append(root.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: name[0]}, section)
+		}
+		valueNode := &yaml.Node{}
+		if err := valueNode.Encode(value.Interface()); err != nil {
+			return nil, err
+		}
+		keyNode := &yaml.Node{Kind: yaml.ScalarNode, Value: name[1], HeadComment: help}
+		section.Content = append(section.Content, keyNode, valueNode)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("# Configuration file of splashscreen-changer\n")
+	buf.WriteString("# See https://github.com/tomacheese/splashscreen-changer/blob/master/docs/settings/file.md for all options\n")
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(root); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// lookupConfigField は、設定項目のキー（例: source.path）から設定項目の値と help タグを取得します。
+func lookupConfigField(config *Config, key string) (reflect.Value, string, error) {
+	var value reflect.Value
+	var help string
+	walkConfigFields(config, func(field configField) {
+		if field.Key == key {
+			value, help = field.Value, field.Field.Tag.Get("help")
+		}
+	})
+	if !value.IsValid() {
+		return reflect.Value{}, "", errors.New("unknown config field " + key)
+	}
+	return value, help, nil
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestConfigWizard(t *testing.T) {
+	source := t.TempDir()
+	invalidDestination := t.TempDir()
+	destination := t.TempDir()
+	if err := os.Mkdir(filepath.Join(destination, "EasyAntiCheat"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	answers := strings.Join([]string{
+		`"` + source + `"`, // source folder, quoted as copied from Explorer
+		"n",                // recursive
+		invalidDestination, // destination without EasyAntiCheat is rejected
+		destination,        // destination
+		"wide",             // width that is not a number is rejected
+		"1280",             // width
+		"",                 // height (default)
+		"diagonal",         // invalid orientation is rejected
+		"landscape",        // orientation
+		"y",                // deduplicate
+	}, "\n") + "\n"
+
+	path := filepath.Join(t.TempDir(), "config.yml")
+	var out bytes.Buffer
+	ctx := &commandContext{In: strings.NewReader(answers), Out: &out}
+	if err := runConfigInit(ctx, path, false, true); err != nil {
+		t.Fatalf("config init failed: %v\n%s", err, out.String())
+	}
+
+	if n := strings.Count(out.String(), "Invalid value"); n != 3 {
+		t.Errorf("Expected 3 rejected answers, got %d:\n%s", n, out.String())
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "# Path to the source directory") {
+		t.Errorf("Expected help comments in the created config:\n%s", data)
+	}
+
+	config, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("Created config is invalid: %v\n%s", err, data)
+	}
+	if config.Source.Path != source || config.Destination.Path != destination {
+		t.Errorf("Unexpected paths: %s, %s", config.Source.Path, config.Destination.Path)
+	}
+	if config.Destination.Width != 1280 || config.Destination.Height != 450 {
+		t.Errorf("Unexpected size: %dx%d", config.Destination.Width, config.Destination.Height)
+	}
+	if config.Source.Orientation != "landscape" || !config.Selection.Deduplicate {
+		t.Errorf("Unexpected selection options: %+v, %+v", config.Source, config.Selection)
+	}
+	if !strings.Contains(string(data), "recursive: false") {
+		t.Errorf("Expected recursive: false in the created config:\n%s", data)
+	}
+}
+
+func TestConfigWizardEndOfInput(t *testing.T) {
+	// The source folder does not exist, and the input ends before it is asked again.
+	// The source folder and the remaining questions are answered with the defaults.
+	path := filepath.Join(t.TempDir(), "config.yml")
+	var out bytes.Buffer
+	ctx := &commandContext{In: strings.NewReader("missing-folder\n"), Out: &out}
+	if err := runConfigInit(ctx, path, false, true); err != nil {
+		t.Fatalf("config init failed: %v\n%s", err, out.String())
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"width: 800", "height: 450", "recursive: true", "orientation: any", "deduplicate: false"} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("Expected %q in the created config:\n%s", want, data)
+		}
+	}
+}
+
+func TestMarshalCommentedConfigQuotesPaths(t *testing.T) {
+	var config Config
+	setDefaults(&config, nil)
+	config.Source.Path = `C:\Users\Name\Pictures\VRChat`
+	config.Destination.Path = `C:\Program Files (x86)\Steam\steamapps\common\VRChat`
+
+	data, err := marshalCommentedConfig(&config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded Config
+	if err := yaml.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Created config is not valid YAML: %v\n%s", err, data)
+	}
+	if decoded.Source.Path != config.Source.Path || decoded.Destination.Path != config.Destination.Path {
+		t.Errorf("Windows paths did not round-trip:\n%s", data)
+	}
+}
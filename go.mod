@@ -5,7 +5,9 @@ go 1.25.0
 toolchain go1.26.5
 
 require (
+	github.com/BurntSushi/toml v1.6.0
 	github.com/andygrunwald/vdf v1.1.0
+	github.com/fsnotify/fsnotify v1.9.0
 	golang.org/x/exp v0.0.0-20260718201538-764159d718ef
 	golang.org/x/image v0.44.0
 	golang.org/x/sys v0.47.0